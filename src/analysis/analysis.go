@@ -0,0 +1,91 @@
+// Package analysis implements static checks on parsed lars programs.
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/parser"
+)
+
+type Severity int
+
+const (
+	ERROR Severity = iota
+	WARNING
+)
+
+func (s Severity) String() string {
+	return [...]string{"error", "warning"}[s]
+}
+
+// Diagnostic is a problem found in a program, by the parser or an analyzer.
+type Diagnostic struct {
+	Line     int
+	Col      int
+	Severity Severity
+	Message  string
+	Hint     string // optional help note
+	Source   string // "parser" or the name of the analyzer
+}
+
+// Analyzer is a single static check.
+type Analyzer struct {
+	Name string
+	Doc  string
+	Run  func(program *ast.Program) []Diagnostic
+}
+
+// Analyzers lists every available analyzer, in the order they run.
+var Analyzers = []*Analyzer{
+	Unreachable,
+	Redeclared,
+	DuplicateParams,
+	Undefined,
+}
+
+// Run runs every analyzer on program and returns their diagnostics sorted
+// by position.
+func Run(program *ast.Program) []Diagnostic {
+	var diags []Diagnostic
+	for _, a := range Analyzers {
+		for _, d := range a.Run(program) {
+			d.Source = a.Name
+			diags = append(diags, d)
+		}
+	}
+
+	Sort(diags)
+	return diags
+}
+
+// Sort sorts diagnostics by position.
+func Sort(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Col < diags[j].Col
+	})
+}
+
+// FromParserErrors converts parser errors to diagnostics.
+func FromParserErrors(errs []parser.ParserError) []Diagnostic {
+	diags := make([]Diagnostic, 0, len(errs))
+	for _, err := range errs {
+		diags = append(diags, Diagnostic{
+			Line:     err.Line,
+			Col:      err.Col,
+			Severity: ERROR,
+			Message:  err.Msg,
+			Hint:     err.Hint,
+			Source:   "parser",
+		})
+	}
+	return diags
+}
+
+func newDiagnostic(severity Severity, line, col int, format string, a ...interface{}) Diagnostic {
+	return Diagnostic{Line: line, Col: col, Severity: severity, Message: fmt.Sprintf(format, a...)}
+}
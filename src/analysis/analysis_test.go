@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/parser"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; x + 1;", nil},
+		{"let f = fn(n) { if (n < 1) { return 1; } return n * f(n - 1); }; f(args[0]);", nil},
+		{"let f = fn() { g() }; let g = fn() { 1 };", nil},
+		{
+			"fn() { return 1; 2; 3 }",
+			[]string{"1:18 warning unreachable: unreachable code"},
+		},
+		{
+			"let a = 1; let a = 2;",
+			[]string{"1:16 warning redeclared: a redeclared in this block (declared at 1:5)"},
+		},
+		{
+			"const a = 1; if (true) { let a = 2; let a = 3; }",
+			[]string{"1:41 warning redeclared: a redeclared in this block (declared at 1:30)"},
+		},
+		{
+			"const a = 1; const a = 2;",
+			[]string{"1:20 error redeclared: cannot redeclare constant a (declared at 1:7)"},
+		},
+		{
+			"fn(x) { let x = 2; x }",
+			[]string{"1:13 warning redeclared: x shadows a parameter"},
+		},
+		{
+			"fn(a, b, a) { a }",
+			[]string{"1:10 error duplicateparams: duplicate parameter a"},
+		},
+		{
+			"let counter = 1; countr + y",
+			[]string{
+				"1:18 warning undefined: undefined: countr (did you mean `counter`?)",
+				"1:27 warning undefined: undefined: y",
+			},
+		},
+		{
+			"fn(x) { let y = x; }; y; if (true) { let z = 1; } z",
+			[]string{
+				"1:23 warning undefined: undefined: y",
+				"1:51 warning undefined: undefined: z",
+			},
+		},
+		{"exit(nope)", []string{"1:6 warning undefined: undefined: nope"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %+v", tt.input, p.Errors())
+		}
+
+		var got []string
+		for _, d := range Run(program) {
+			s := fmt.Sprintf("%d:%d %s %s: %s", d.Line, d.Col, d.Severity, d.Source, d.Message)
+			if d.Hint != "" {
+				s += fmt.Sprintf(" (%s)", d.Hint)
+			}
+			got = append(got, s)
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("wrong diagnostics for %q.\nwant=%q\ngot =%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestUndefinedHintTies(t *testing.T) {
+	// abq is one edit away from every name; the first in sorted order wins
+	program := parser.New(lexer.New("let abz = 1; let aby = 2; let abx = 3; abq")).ParseProgram()
+
+	for range 20 {
+		diags := Run(program)
+		if len(diags) != 1 || diags[0].Hint != "did you mean `abx`?" {
+			t.Fatalf("wrong diagnostics %+v", diags)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/evaluator"
+	"github.com/salty-max/lars/src/suggest"
+	"github.com/salty-max/lars/src/token"
+)
+
+// Unreachable reports statements that follow a return in the same block.
+var Unreachable = &Analyzer{
+	Name: "unreachable",
+	Doc:  "report statements that can never run because they follow a return",
+	Run: func(program *ast.Program) []Diagnostic {
+		var diags []Diagnostic
+
+		check := func(stmts []ast.Statement) {
+			for i, stmt := range stmts {
+				if _, ok := stmt.(*ast.ReturnStatement); ok && i+1 < len(stmts) {
+					start, _ := ast.Span(stmts[i+1])
+					diags = append(diags, newDiagnostic(WARNING, start.Line, start.Col, "unreachable code"))
+					return
+				}
+			}
+		}
+
+		check(program.Statements)
+		ast.Inspect(program, func(n ast.Node) bool {
+			if block, ok := n.(*ast.BlockStatement); ok {
+				check(block.Statements)
+			}
+			return true
+		})
+
+		return diags
+	},
+}
+
+// Redeclared reports names declared twice in the same block. Redeclaring a
+// constant is an error; redeclaring a variable shadows it and is a warning.
+var Redeclared = &Analyzer{
+	Name: "redeclared",
+	Doc:  "report let and const declarations that reuse a name in the same block",
+	Run: func(program *ast.Program) []Diagnostic {
+		var diags []Diagnostic
+
+		check := func(stmts []ast.Statement, params []*ast.Identifier) {
+			declared := map[string]*ast.VarDeclStatement{}
+			isParam := map[string]bool{}
+			for _, p := range params {
+				isParam[p.Value] = true
+			}
+
+			for _, stmt := range stmts {
+				decl, ok := stmt.(*ast.VarDeclStatement)
+				if !ok {
+					continue
+				}
+
+				name := decl.Name.Value
+				tok := decl.Name.Token
+				if prev, ok := declared[name]; ok {
+					if prev.IsConst {
+						diags = append(diags, newDiagnostic(ERROR, tok.Line, tok.Col,
+							"cannot redeclare constant %s (declared at %d:%d)",
+							name, prev.Name.Token.Line, prev.Name.Token.Col))
+					} else {
+						diags = append(diags, newDiagnostic(WARNING, tok.Line, tok.Col,
+							"%s redeclared in this block (declared at %d:%d)",
+							name, prev.Name.Token.Line, prev.Name.Token.Col))
+					}
+				} else if isParam[name] {
+					diags = append(diags, newDiagnostic(WARNING, tok.Line, tok.Col,
+						"%s shadows a parameter", name))
+				}
+				declared[name] = decl
+			}
+		}
+
+		params := map[*ast.BlockStatement][]*ast.Identifier{}
+
+		check(program.Statements, nil)
+		ast.Inspect(program, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FunctionLiteral:
+				params[n.Body] = n.Parameters
+			case *ast.BlockStatement:
+				check(n.Statements, params[n])
+			}
+			return true
+		})
+
+		return diags
+	},
+}
+
+// DuplicateParams reports functions that declare a parameter twice.
+var DuplicateParams = &Analyzer{
+	Name: "duplicateparams",
+	Doc:  "report functions with two parameters of the same name",
+	Run: func(program *ast.Program) []Diagnostic {
+		var diags []Diagnostic
+
+		ast.Inspect(program, func(n ast.Node) bool {
+			fn, ok := n.(*ast.FunctionLiteral)
+			if !ok {
+				return true
+			}
+
+			seen := map[string]bool{}
+			for _, p := range fn.Parameters {
+				if seen[p.Value] {
+					diags = append(diags, newDiagnostic(ERROR, p.Token.Line, p.Token.Col,
+						"duplicate parameter %s", p.Value))
+				}
+				seen[p.Value] = true
+			}
+			return true
+		})
+
+		return diags
+	},
+}
+
+// Predeclared lists the names a program may use without declaring them,
+// besides the builtins. Hosts that bind their own globals can extend it.
+var Predeclared = []string{"args"}
+
+// Undefined reports identifiers that are not declared in any enclosing
+// scope. A name counts as declared anywhere in its block, since functions
+// may refer to bindings made after them.
+var Undefined = &Analyzer{
+	Name: "undefined",
+	Doc:  "report identifiers that are never declared",
+	Run: func(program *ast.Program) []Diagnostic {
+		u := &undefinedChecker{}
+
+		global := newScope(nil)
+		for _, name := range Predeclared {
+			global.names[name] = true
+		}
+		for _, name := range evaluator.BuiltinNames() {
+			global.names[name] = true
+		}
+
+		u.statements(program.Statements, global)
+		return u.diags
+	},
+}
+
+type scope struct {
+	names map[string]bool
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: map[string]bool{}, outer: outer}
+}
+
+func (s *scope) declared(name string) bool {
+	for ; s != nil; s = s.outer {
+		if s.names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// visible returns the names declared in s and its outer scopes, sorted so
+// that suggestions do not depend on map order.
+func (s *scope) visible() []string {
+	seen := map[string]bool{}
+	for ; s != nil; s = s.outer {
+		for name := range s.names {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+type undefinedChecker struct {
+	diags []Diagnostic
+}
+
+func (u *undefinedChecker) statements(stmts []ast.Statement, s *scope) {
+	for _, stmt := range stmts {
+		if decl, ok := stmt.(*ast.VarDeclStatement); ok {
+			s.names[decl.Name.Value] = true
+		}
+	}
+
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.VarDeclStatement:
+			u.node(stmt.Value, s)
+		default:
+			u.node(stmt, s)
+		}
+	}
+}
+
+func (u *undefinedChecker) node(node ast.Node, s *scope) {
+	if node == nil {
+		return
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			if !s.declared(n.Value) {
+				d := newDiagnostic(WARNING, n.Token.Line, n.Token.Col, "undefined: %s", n.Value)
+				candidates := append(s.visible(), token.Keywords()...)
+				if match, ok := suggest.Closest(n.Value, candidates); ok {
+					d.Hint = fmt.Sprintf("did you mean `%s`?", match)
+				}
+				u.diags = append(u.diags, d)
+			}
+		case *ast.FunctionLiteral:
+			fnScope := newScope(s)
+			for _, p := range n.Parameters {
+				fnScope.names[p.Value] = true
+			}
+			if n.Body != nil {
+				u.statements(n.Body.Statements, fnScope)
+			}
+			return false
+		case *ast.BlockStatement:
+			u.statements(n.Statements, newScope(s))
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,426 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/salty-max/lars/src/token"
+)
+
+// jsonToken is the JSON form of a token.Token.
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Line    int             `json:"line"`
+	Col     int             `json:"col"`
+}
+
+// jsonNode is the JSON form of every node. Kind names the node type and
+// decides which of the other fields are meaningful.
+type jsonNode struct {
+	Kind        string            `json:"kind"`
+	Token       *jsonToken        `json:"token,omitempty"`
+	Rbrace      *jsonToken        `json:"rbrace,omitempty"`
+	Rbracket    *jsonToken        `json:"rbracket,omitempty"`
+	Rparen      *jsonToken        `json:"rparen,omitempty"`
+	Property    *jsonToken        `json:"property,omitempty"`
+	Const       bool              `json:"const,omitempty"`
+	Name        json.RawMessage   `json:"name,omitempty"`
+	Operator    string            `json:"operator,omitempty"`
+	Left        json.RawMessage   `json:"left,omitempty"`
+	Right       json.RawMessage   `json:"right,omitempty"`
+	Condition   json.RawMessage   `json:"condition,omitempty"`
+	Consequence json.RawMessage   `json:"consequence,omitempty"`
+	Alternative json.RawMessage   `json:"alternative,omitempty"`
+	Function    json.RawMessage   `json:"function,omitempty"`
+	Parameters  []json.RawMessage `json:"parameters,omitempty"`
+	Arguments   []json.RawMessage `json:"arguments,omitempty"`
+	Elements    []json.RawMessage `json:"elements,omitempty"`
+	Keys        []json.RawMessage `json:"keys,omitempty"`
+	Values      []json.RawMessage `json:"values,omitempty"`
+	Index       json.RawMessage   `json:"index,omitempty"`
+	Object      json.RawMessage   `json:"object,omitempty"`
+	Target      json.RawMessage   `json:"target,omitempty"`
+	Body        json.RawMessage   `json:"body,omitempty"`
+	Statements  []json.RawMessage `json:"statements,omitempty"`
+	Expression  json.RawMessage   `json:"expression,omitempty"`
+	Value       json.RawMessage   `json:"value,omitempty"`
+}
+
+// MarshalJSON encodes node and all of its children as JSON. Every node is
+// an object with a "kind" discriminator, its fields and its token.
+func MarshalJSON(node Node) ([]byte, error) {
+	return encodeNode(node)
+}
+
+// UnmarshalJSON decodes a node previously encoded by MarshalJSON.
+func UnmarshalJSON(data []byte) (Node, error) {
+	return decodeNode(data)
+}
+
+func encodeNode(node Node) (json.RawMessage, error) {
+	var (
+		j   jsonNode
+		err error
+	)
+
+	child := func(n Node) json.RawMessage {
+		if err != nil || isNil(n) {
+			return nil
+		}
+		var raw json.RawMessage
+		raw, err = encodeNode(n)
+		return raw
+	}
+
+	value := func(v any) json.RawMessage {
+		if err != nil {
+			return nil
+		}
+		var raw json.RawMessage
+		raw, err = json.Marshal(v)
+		return raw
+	}
+
+	switch n := node.(type) {
+	// Statements
+	case *Program:
+		j.Kind = "Program"
+		j.Statements = make([]json.RawMessage, 0, len(n.Statements))
+		for _, s := range n.Statements {
+			j.Statements = append(j.Statements, child(s))
+		}
+	case *VarDeclStatement:
+		j.Kind = "VarDeclStatement"
+		j.Token = encodeToken(n.Token)
+		j.Const = n.IsConst
+		j.Name = child(n.Name)
+		j.Value = child(n.Value)
+	case *ReturnStatement:
+		j.Kind = "ReturnStatement"
+		j.Token = encodeToken(n.Token)
+		j.Value = child(n.ReturnValue)
+	case *BlockStatement:
+		j.Kind = "BlockStatement"
+		j.Token = encodeToken(n.Token)
+		j.Rbrace = encodeToken(n.Rbrace)
+		j.Statements = make([]json.RawMessage, 0, len(n.Statements))
+		for _, s := range n.Statements {
+			j.Statements = append(j.Statements, child(s))
+		}
+	case *ExpressionStatement:
+		j.Kind = "ExpressionStatement"
+		j.Token = encodeToken(n.Token)
+		j.Expression = child(n.Expression)
+
+	// Expressions
+	case *PrefixExpression:
+		j.Kind = "PrefixExpression"
+		j.Token = encodeToken(n.Token)
+		j.Operator = n.Operator
+		j.Right = child(n.Right)
+	case *InfixExpression:
+		j.Kind = "InfixExpression"
+		j.Token = encodeToken(n.Token)
+		j.Operator = n.Operator
+		j.Left = child(n.Left)
+		j.Right = child(n.Right)
+	case *IfExpression:
+		j.Kind = "IfExpression"
+		j.Token = encodeToken(n.Token)
+		j.Condition = child(n.Condition)
+		j.Consequence = child(n.Consequence)
+		j.Alternative = child(n.Alternative)
+	case *FunctionLiteral:
+		j.Kind = "FunctionLiteral"
+		j.Token = encodeToken(n.Token)
+		j.Parameters = make([]json.RawMessage, 0, len(n.Parameters))
+		for _, p := range n.Parameters {
+			j.Parameters = append(j.Parameters, child(p))
+		}
+		j.Body = child(n.Body)
+	case *CallExpression:
+		j.Kind = "CallExpression"
+		j.Token = encodeToken(n.Token)
+		j.Rparen = encodeToken(n.Rparen)
+		j.Function = child(n.Function)
+		j.Arguments = make([]json.RawMessage, 0, len(n.Arguments))
+		for _, a := range n.Arguments {
+			j.Arguments = append(j.Arguments, child(a))
+		}
+	case *ArrayLiteral:
+		j.Kind = "ArrayLiteral"
+		j.Token = encodeToken(n.Token)
+		j.Rbracket = encodeToken(n.Rbracket)
+		j.Elements = make([]json.RawMessage, 0, len(n.Elements))
+		for _, el := range n.Elements {
+			j.Elements = append(j.Elements, child(el))
+		}
+	case *HashLiteral:
+		j.Kind = "HashLiteral"
+		j.Token = encodeToken(n.Token)
+		j.Rbrace = encodeToken(n.Rbrace)
+		j.Keys = make([]json.RawMessage, 0, len(n.Pairs))
+		j.Values = make([]json.RawMessage, 0, len(n.Pairs))
+		for _, pair := range n.Pairs {
+			j.Keys = append(j.Keys, child(pair.Key))
+			j.Values = append(j.Values, child(pair.Value))
+		}
+	case *IndexExpression:
+		j.Kind = "IndexExpression"
+		j.Token = encodeToken(n.Token)
+		j.Left = child(n.Left)
+		j.Index = child(n.Index)
+	case *MemberExpression:
+		j.Kind = "MemberExpression"
+		j.Token = encodeToken(n.Token)
+		j.Property = encodeToken(n.Property)
+		j.Object = child(n.Object)
+	case *AssignExpression:
+		j.Kind = "AssignExpression"
+		j.Token = encodeToken(n.Token)
+		j.Target = child(n.Target)
+		j.Value = child(n.Value)
+	case *Identifier:
+		j.Kind = "Identifier"
+		j.Token = encodeToken(n.Token)
+		j.Value = value(n.Value)
+	case *IntegerLiteral:
+		j.Kind = "IntegerLiteral"
+		j.Token = encodeToken(n.Token)
+		j.Value = value(n.Value)
+	case *FloatLiteral:
+		j.Kind = "FloatLiteral"
+		j.Token = encodeToken(n.Token)
+		j.Value = value(n.Value)
+	case *StringLiteral:
+		j.Kind = "StringLiteral"
+		j.Token = encodeToken(n.Token)
+		j.Value = value(n.Value)
+	case *Boolean:
+		j.Kind = "Boolean"
+		j.Token = encodeToken(n.Token)
+		j.Value = value(n.Value)
+	case *Null:
+		j.Kind = "Null"
+		j.Token = encodeToken(n.Token)
+
+	default:
+		return nil, fmt.Errorf("ast: cannot encode node of type %T", n)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(j)
+}
+
+func encodeToken(t token.Token) *jsonToken {
+	return &jsonToken{Type: t.Type, Literal: t.Literal, Line: t.Line, Col: t.Col}
+}
+
+func decodeToken(t *jsonToken) token.Token {
+	if t == nil {
+		return token.Token{}
+	}
+	return token.Token{Type: t.Type, Literal: t.Literal, Line: t.Line, Col: t.Col}
+}
+
+// isNil reports whether n is nil or a typed nil pointer, which both encode
+// as an absent field.
+func isNil(n Node) bool {
+	switch n := n.(type) {
+	case nil:
+		return true
+	case *Identifier:
+		return n == nil
+	case *BlockStatement:
+		return n == nil
+	}
+	return false
+}
+
+func decodeNode(data []byte) (Node, error) {
+	var j jsonNode
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+
+	var err error
+
+	tok := func() token.Token { return decodeToken(j.Token) }
+
+	child := func(raw json.RawMessage) Node {
+		if err != nil || len(raw) == 0 || string(raw) == "null" {
+			return nil
+		}
+		var n Node
+		n, err = decodeNode(raw)
+		return n
+	}
+
+	expr := func(raw json.RawMessage) Expression {
+		n := child(raw)
+		if n == nil {
+			return nil
+		}
+		e, ok := n.(Expression)
+		if !ok && err == nil {
+			err = fmt.Errorf("ast: %s is not an expression", kindOf(n))
+		}
+		return e
+	}
+
+	stmts := func(list []json.RawMessage) []Statement {
+		out := make([]Statement, 0, len(list))
+		for _, raw := range list {
+			n := child(raw)
+			if n == nil {
+				continue
+			}
+			s, ok := n.(Statement)
+			if !ok && err == nil {
+				err = fmt.Errorf("ast: %s is not a statement", kindOf(n))
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+
+	ident := func(raw json.RawMessage) *Identifier {
+		n := child(raw)
+		if n == nil {
+			return nil
+		}
+		i, ok := n.(*Identifier)
+		if !ok && err == nil {
+			err = fmt.Errorf("ast: %s is not an Identifier", kindOf(n))
+		}
+		return i
+	}
+
+	block := func(raw json.RawMessage) *BlockStatement {
+		n := child(raw)
+		if n == nil {
+			return nil
+		}
+		b, ok := n.(*BlockStatement)
+		if !ok && err == nil {
+			err = fmt.Errorf("ast: %s is not a BlockStatement", kindOf(n))
+		}
+		return b
+	}
+
+	value := func(v any) {
+		if err == nil {
+			err = json.Unmarshal(j.Value, v)
+		}
+	}
+
+	var node Node
+
+	switch j.Kind {
+	// Statements
+	case "Program":
+		node = &Program{Statements: stmts(j.Statements)}
+	case "VarDeclStatement":
+		node = &VarDeclStatement{IsConst: j.Const, Token: tok(), Name: ident(j.Name), Value: expr(j.Value)}
+	case "ReturnStatement":
+		node = &ReturnStatement{Token: tok(), ReturnValue: expr(j.Value)}
+	case "BlockStatement":
+		node = &BlockStatement{
+			Token:      tok(),
+			Statements: stmts(j.Statements),
+			Rbrace:     decodeToken(j.Rbrace),
+		}
+	case "ExpressionStatement":
+		node = &ExpressionStatement{Token: tok(), Expression: expr(j.Expression)}
+
+	// Expressions
+	case "PrefixExpression":
+		node = &PrefixExpression{Token: tok(), Operator: j.Operator, Right: expr(j.Right)}
+	case "InfixExpression":
+		node = &InfixExpression{
+			Token:    tok(),
+			Left:     expr(j.Left),
+			Operator: j.Operator,
+			Right:    expr(j.Right),
+		}
+	case "IfExpression":
+		node = &IfExpression{
+			Token:       tok(),
+			Condition:   expr(j.Condition),
+			Consequence: block(j.Consequence),
+			Alternative: block(j.Alternative),
+		}
+	case "FunctionLiteral":
+		fl := &FunctionLiteral{Token: tok(), Parameters: []*Identifier{}, Body: block(j.Body)}
+		for _, raw := range j.Parameters {
+			fl.Parameters = append(fl.Parameters, ident(raw))
+		}
+		node = fl
+	case "CallExpression":
+		ce := &CallExpression{Token: tok(), Function: expr(j.Function), Arguments: []Expression{}, Rparen: decodeToken(j.Rparen)}
+		for _, raw := range j.Arguments {
+			ce.Arguments = append(ce.Arguments, expr(raw))
+		}
+		node = ce
+	case "ArrayLiteral":
+		al := &ArrayLiteral{Token: tok(), Elements: []Expression{}, Rbracket: decodeToken(j.Rbracket)}
+		for _, raw := range j.Elements {
+			al.Elements = append(al.Elements, expr(raw))
+		}
+		node = al
+	case "HashLiteral":
+		if len(j.Keys) != len(j.Values) {
+			return nil, fmt.Errorf("ast: HashLiteral has %d keys but %d values", len(j.Keys), len(j.Values))
+		}
+		hl := &HashLiteral{Token: tok(), Pairs: []HashPair{}, Rbrace: decodeToken(j.Rbrace)}
+		for i := range j.Keys {
+			hl.Pairs = append(hl.Pairs, HashPair{Key: expr(j.Keys[i]), Value: expr(j.Values[i])})
+		}
+		node = hl
+	case "IndexExpression":
+		node = &IndexExpression{Token: tok(), Left: expr(j.Left), Index: expr(j.Index)}
+	case "MemberExpression":
+		node = &MemberExpression{Token: tok(), Object: expr(j.Object), Property: decodeToken(j.Property)}
+	case "AssignExpression":
+		node = &AssignExpression{Token: tok(), Target: expr(j.Target), Value: expr(j.Value)}
+	case "Identifier":
+		i := &Identifier{Token: tok()}
+		value(&i.Value)
+		node = i
+	case "IntegerLiteral":
+		il := &IntegerLiteral{Token: tok()}
+		value(&il.Value)
+		node = il
+	case "FloatLiteral":
+		fl := &FloatLiteral{Token: tok()}
+		value(&fl.Value)
+		node = fl
+	case "StringLiteral":
+		sl := &StringLiteral{Token: tok()}
+		value(&sl.Value)
+		node = sl
+	case "Boolean":
+		b := &Boolean{Token: tok()}
+		value(&b.Value)
+		node = b
+	case "Null":
+		node = &Null{Token: tok()}
+
+	default:
+		return nil, fmt.Errorf("ast: unknown node kind %q", j.Kind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func kindOf(n Node) string {
+	return fmt.Sprintf("%T", n)[len("*ast."):]
+}
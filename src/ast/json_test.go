@@ -0,0 +1,127 @@
+package ast_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/salty-max/lars/src/ast"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		"let x = 5;",
+		"const PI = 3.14;",
+		"return -a * (b + c) % 2;",
+		"if (x < y) { x } else { y }",
+		"let add = fn(x, y) { return x + y; }; add(1, 2.5);",
+		"fn() { null }();",
+		"!true != false",
+		`let h = {"a": [1], 2: {}}; h["a"];`,
+		"db.rows[0] = req.body.id; db.close();",
+	}
+
+	for _, input := range tests {
+		program := parse(t, input)
+
+		data, err := ast.MarshalJSON(program)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%q) failed: %s", input, err)
+		}
+
+		node, err := ast.UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON(%q) failed: %s", input, err)
+		}
+
+		if node.String() != program.String() {
+			t.Errorf("round trip of %q changed the program. want=%q, got=%q",
+				input, program.String(), node.String())
+		}
+
+		again, err := ast.MarshalJSON(node)
+		if err != nil {
+			t.Fatalf("MarshalJSON of decoded %q failed: %s", input, err)
+		}
+		if !bytes.Equal(data, again) {
+			t.Errorf("round trip of %q is not stable.\nfirst =%s\nsecond=%s", input, data, again)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	program := parse(t, "1 + x")
+
+	data, err := ast.MarshalJSON(program)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %s", err)
+	}
+
+	var decoded struct {
+		Kind       string `json:"kind"`
+		Statements []struct {
+			Kind       string `json:"kind"`
+			Expression struct {
+				Kind     string `json:"kind"`
+				Operator string `json:"operator"`
+				Token    struct {
+					Type    string `json:"type"`
+					Literal string `json:"literal"`
+					Line    int    `json:"line"`
+					Col     int    `json:"col"`
+				} `json:"token"`
+				Right struct {
+					Kind  string `json:"kind"`
+					Value string `json:"value"`
+				} `json:"right"`
+			} `json:"expression"`
+		} `json:"statements"`
+	}
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %s", err)
+	}
+
+	if decoded.Kind != "Program" || len(decoded.Statements) != 1 {
+		t.Fatalf("wrong program encoding: %s", data)
+	}
+
+	expr := decoded.Statements[0].Expression
+	if decoded.Statements[0].Kind != "ExpressionStatement" || expr.Kind != "InfixExpression" {
+		t.Fatalf("wrong statement encoding: %s", data)
+	}
+	if expr.Operator != "+" || expr.Token.Line != 1 || expr.Token.Col != 3 {
+		t.Errorf("wrong infix encoding: %s", data)
+	}
+	if expr.Right.Kind != "Identifier" || expr.Right.Value != "x" {
+		t.Errorf("wrong identifier encoding: %s", data)
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind": "Bogus"}`, `ast: unknown node kind "Bogus"`},
+		{
+			`{"kind": "Program", "statements": [{"kind": "Identifier", "value": "x"}]}`,
+			"ast: Identifier is not a statement",
+		},
+		{
+			`{"kind": "VarDeclStatement", "name": {"kind": "Null"}}`,
+			"ast: Null is not an Identifier",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.UnmarshalJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected error for %s", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/salty-max/lars/src/token"
+)
+
+// Pos is a line and column in the source, both starting at 1.
+type Pos struct {
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string { return fmt.Sprintf("%d:%d", p.Line, p.Col) }
+
+// IsValid reports whether p points into the source.
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+func (p Pos) before(q Pos) bool {
+	return p.Line < q.Line || (p.Line == q.Line && p.Col < q.Col)
+}
+
+// Span returns the position of the first and last character of node. The
+// span covers every token the node keeps; delimiters the parser drops,
+// such as the parentheses around a grouped expression, are not included.
+func Span(node Node) (start, end Pos) {
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+
+		for _, tok := range nodeTokens(n) {
+			if tok.Line == 0 {
+				continue
+			}
+
+			first := Pos{Line: tok.Line, Col: tok.Col}
+			last := tokenEnd(tok)
+
+			if !start.IsValid() || first.before(start) {
+				start = first
+			}
+			if !end.IsValid() || end.before(last) {
+				end = last
+			}
+		}
+		return true
+	})
+
+	return start, end
+}
+
+// tokenEnd returns the position of the last character of tok.
+func tokenEnd(tok token.Token) Pos {
+	literal := tok.Literal
+	if tok.Type == token.STRING {
+		literal = `"` + literal + `"`
+	}
+
+	if i := strings.LastIndexByte(literal, '\n'); i >= 0 {
+		return Pos{Line: tok.Line + strings.Count(literal, "\n"), Col: len(literal) - i - 1}
+	}
+	return Pos{Line: tok.Line, Col: tok.Col + max(len(literal), 1) - 1}
+}
+
+// nodeTokens returns the tokens node itself holds, not those of its children.
+func nodeTokens(node Node) []token.Token {
+	switch n := node.(type) {
+	case *VarDeclStatement:
+		return []token.Token{n.Token}
+	case *ReturnStatement:
+		return []token.Token{n.Token}
+	case *BlockStatement:
+		return []token.Token{n.Token, n.Rbrace}
+	case *ExpressionStatement:
+		return []token.Token{n.Token}
+	case *PrefixExpression:
+		return []token.Token{n.Token}
+	case *InfixExpression:
+		return []token.Token{n.Token}
+	case *IfExpression:
+		return []token.Token{n.Token}
+	case *FunctionLiteral:
+		return []token.Token{n.Token}
+	case *CallExpression:
+		return []token.Token{n.Token, n.Rparen}
+	case *ArrayLiteral:
+		return []token.Token{n.Token, n.Rbracket}
+	case *HashLiteral:
+		return []token.Token{n.Token, n.Rbrace}
+	case *IndexExpression:
+		return []token.Token{n.Token}
+	case *MemberExpression:
+		return []token.Token{n.Token, n.Property}
+	case *AssignExpression:
+		return []token.Token{n.Token}
+	case *Identifier:
+		return []token.Token{n.Token}
+	case *IntegerLiteral:
+		return []token.Token{n.Token}
+	case *FloatLiteral:
+		return []token.Token{n.Token}
+	case *StringLiteral:
+		return []token.Token{n.Token}
+	case *Boolean:
+		return []token.Token{n.Token}
+	case *Null:
+		return []token.Token{n.Token}
+	}
+	return nil
+}
+
+// Dump writes an indented tree of node to w, one node per line with its
+// kind, its most telling field and its span.
+func Dump(w io.Writer, node Node) error {
+	d := &dumper{w: w}
+	Walk(d, node)
+	return d.err
+}
+
+type dumper struct {
+	w     io.Writer
+	depth int
+	err   error
+}
+
+func (d *dumper) Visit(node Node) Visitor {
+	if node == nil {
+		d.depth--
+		return nil
+	}
+
+	kind := fmt.Sprintf("%T", node)[len("*ast."):]
+	line := strings.Repeat("  ", d.depth) + kind
+	if detail := dumpDetail(node); detail != "" {
+		line += " " + detail
+	}
+	if start, end := Span(node); start.IsValid() {
+		line += fmt.Sprintf(" [%s-%s]", start, end)
+	}
+
+	if d.err == nil {
+		_, d.err = fmt.Fprintln(d.w, line)
+	}
+
+	d.depth++
+	return d
+}
+
+func dumpDetail(node Node) string {
+	switch n := node.(type) {
+	case *VarDeclStatement:
+		return n.Token.Literal
+	case *PrefixExpression:
+		return n.Operator
+	case *InfixExpression:
+		return n.Operator
+	case *MemberExpression:
+		return n.Property.Literal
+	case *Identifier:
+		return n.Value
+	case *IntegerLiteral:
+		return n.Token.Literal
+	case *FloatLiteral:
+		return n.Token.Literal
+	case *StringLiteral:
+		return fmt.Sprintf("%q", n.Value)
+	case *Boolean:
+		return n.Token.Literal
+	}
+	return ""
+}
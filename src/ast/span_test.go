@@ -0,0 +1,66 @@
+package ast_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/salty-max/lars/src/ast"
+)
+
+func TestSpan(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedStart string
+		expectedEnd   string
+	}{
+		{"x", "1:1", "1:1"},
+		{"let count = 10;", "1:1", "1:14"},
+		{"1 + foo * 3", "1:1", "1:11"},
+		{`print("hello")`, "1:1", "1:14"},
+		{"[\n  1,\n  2\n]", "1:1", "4:1"},
+		{"if (x) {\n  1\n} else {\n  2\n}", "1:1", "5:1"},
+		{"fn(a) {\n  return a;\n}", "1:1", "3:1"},
+		{"[1, 2][0]", "1:1", "1:8"},
+		{"req.headers.host", "1:1", "1:16"},
+		{"user.name = \"ada\"", "1:1", "1:17"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+
+		start, end := ast.Span(program.Statements[0])
+		if start.String() != tt.expectedStart || end.String() != tt.expectedEnd {
+			t.Errorf("input %q: wrong span. want=%s-%s, got=%s-%s",
+				tt.input, tt.expectedStart, tt.expectedEnd, start, end)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	program := parse(t, "let add = fn(x) { x + 1 };\nadd(-2)")
+
+	var buf bytes.Buffer
+	if err := ast.Dump(&buf, program); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `Program [1:1-2:7]
+  VarDeclStatement let [1:1-1:25]
+    Identifier add [1:5-1:7]
+    FunctionLiteral [1:11-1:25]
+      Identifier x [1:14-1:14]
+      BlockStatement [1:17-1:25]
+        ExpressionStatement [1:19-1:23]
+          InfixExpression + [1:19-1:23]
+            Identifier x [1:19-1:19]
+            IntegerLiteral 1 [1:23-1:23]
+  ExpressionStatement [2:1-2:7]
+    CallExpression [2:1-2:7]
+      Identifier add [2:1-2:3]
+      PrefixExpression - [2:5-2:6]
+        IntegerLiteral 2 [2:6-2:6]
+`
+	if buf.String() != expected {
+		t.Errorf("wrong dump.\nwant:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
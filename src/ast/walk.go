@@ -0,0 +1,314 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Statements
+	case *Program:
+		walkStatements(v, n.Statements)
+	case *VarDeclStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			Walk(v, n.ReturnValue)
+		}
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+
+	// Expressions
+	case *PrefixExpression:
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+	case *InfixExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+	case *IfExpression:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *CallExpression:
+		if n.Function != nil {
+			Walk(v, n.Function)
+		}
+		walkExpressions(v, n.Arguments)
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			if pair.Key != nil {
+				Walk(v, pair.Key)
+			}
+			if pair.Value != nil {
+				Walk(v, pair.Value)
+			}
+		}
+	case *IndexExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+	case *MemberExpression:
+		if n.Object != nil {
+			Walk(v, n.Object)
+		}
+	case *AssignExpression:
+		if n.Target != nil {
+			Walk(v, n.Target)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean, *Null:
+		// nothing to do
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(v Visitor, list []Statement) {
+	for _, s := range list {
+		if s != nil {
+			Walk(v, s)
+		}
+	}
+}
+
+func walkExpressions(v Visitor, list []Expression) {
+	for _, e := range list {
+		if e != nil {
+			Walk(v, e)
+		}
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite traverses an AST in depth-first order and replaces every node
+// with the result of f. Children are rewritten before their parent, so f
+// always sees a node whose subtrees have already been rewritten. Rewrite
+// returns the replacement for node itself.
+//
+// Returning nil from f removes a statement from its enclosing Program or
+// BlockStatement. Anywhere else the replacement must fit the field it is
+// stored in: an Expression for an expression slot, an *Identifier for a
+// name or parameter, a *BlockStatement for a body. Rewrite panics if it
+// does not.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	// Statements
+	case *Program:
+		n.Statements = rewriteStatements(n.Statements, f)
+	case *VarDeclStatement:
+		if n.Name != nil {
+			n.Name = rewriteIdentifier(n.Name, f)
+		}
+		if n.Value != nil {
+			n.Value = rewriteExpression(n.Value, f)
+		}
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			n.ReturnValue = rewriteExpression(n.ReturnValue, f)
+		}
+	case *BlockStatement:
+		n.Statements = rewriteStatements(n.Statements, f)
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			n.Expression = rewriteExpression(n.Expression, f)
+		}
+
+	// Expressions
+	case *PrefixExpression:
+		if n.Right != nil {
+			n.Right = rewriteExpression(n.Right, f)
+		}
+	case *InfixExpression:
+		if n.Left != nil {
+			n.Left = rewriteExpression(n.Left, f)
+		}
+		if n.Right != nil {
+			n.Right = rewriteExpression(n.Right, f)
+		}
+	case *IfExpression:
+		if n.Condition != nil {
+			n.Condition = rewriteExpression(n.Condition, f)
+		}
+		if n.Consequence != nil {
+			n.Consequence = rewriteBlock(n.Consequence, f)
+		}
+		if n.Alternative != nil {
+			n.Alternative = rewriteBlock(n.Alternative, f)
+		}
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			n.Parameters[i] = rewriteIdentifier(p, f)
+		}
+		if n.Body != nil {
+			n.Body = rewriteBlock(n.Body, f)
+		}
+	case *CallExpression:
+		if n.Function != nil {
+			n.Function = rewriteExpression(n.Function, f)
+		}
+		for i, a := range n.Arguments {
+			if a != nil {
+				n.Arguments[i] = rewriteExpression(a, f)
+			}
+		}
+	case *ArrayLiteral:
+		for i, el := range n.Elements {
+			if el != nil {
+				n.Elements[i] = rewriteExpression(el, f)
+			}
+		}
+	case *HashLiteral:
+		for i, pair := range n.Pairs {
+			if pair.Key != nil {
+				n.Pairs[i].Key = rewriteExpression(pair.Key, f)
+			}
+			if pair.Value != nil {
+				n.Pairs[i].Value = rewriteExpression(pair.Value, f)
+			}
+		}
+	case *IndexExpression:
+		if n.Left != nil {
+			n.Left = rewriteExpression(n.Left, f)
+		}
+		if n.Index != nil {
+			n.Index = rewriteExpression(n.Index, f)
+		}
+	case *MemberExpression:
+		if n.Object != nil {
+			n.Object = rewriteExpression(n.Object, f)
+		}
+	case *AssignExpression:
+		if n.Target != nil {
+			n.Target = rewriteExpression(n.Target, f)
+		}
+		if n.Value != nil {
+			n.Value = rewriteExpression(n.Value, f)
+		}
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean, *Null:
+		// nothing to do
+
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+func rewriteStatements(list []Statement, f func(Node) Node) []Statement {
+	out := list[:0]
+	for _, s := range list {
+		if s == nil {
+			continue
+		}
+
+		r := Rewrite(s, f)
+		if r == nil {
+			continue
+		}
+
+		stmt, ok := r.(Statement)
+		if !ok {
+			panic(fmt.Sprintf("ast.Rewrite: cannot replace statement %T with %T", s, r))
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
+
+func rewriteExpression(e Expression, f func(Node) Node) Expression {
+	r := Rewrite(e, f)
+	if r == nil {
+		return nil
+	}
+
+	expr, ok := r.(Expression)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace expression %T with %T", e, r))
+	}
+	return expr
+}
+
+func rewriteIdentifier(i *Identifier, f func(Node) Node) *Identifier {
+	r := Rewrite(i, f)
+	ident, ok := r.(*Identifier)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace *ast.Identifier with %T", r))
+	}
+	return ident
+}
+
+func rewriteBlock(b *BlockStatement, f func(Node) Node) *BlockStatement {
+	r := Rewrite(b, f)
+	block, ok := r.(*BlockStatement)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace *ast.BlockStatement with %T", r))
+	}
+	return block
+}
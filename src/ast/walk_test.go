@@ -0,0 +1,172 @@
+package ast_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %+v", input, p.Errors())
+	}
+	return program
+}
+
+func TestInspect(t *testing.T) {
+	input := `let add = fn(x, y) { return x + y; }; if (add(1, -2.5) > 0) { true } else { null }`
+
+	program := parse(t, input)
+
+	var kinds []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n != nil {
+			kinds = append(kinds, strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+		}
+		return true
+	})
+
+	expected := []string{
+		"Program",
+		"VarDeclStatement", "Identifier",
+		"FunctionLiteral", "Identifier", "Identifier",
+		"BlockStatement", "ReturnStatement", "InfixExpression", "Identifier", "Identifier",
+		"ExpressionStatement", "IfExpression",
+		"InfixExpression", "CallExpression", "Identifier", "IntegerLiteral",
+		"PrefixExpression", "FloatLiteral", "IntegerLiteral",
+		"BlockStatement", "ExpressionStatement", "Boolean",
+		"BlockStatement", "ExpressionStatement", "Null",
+	}
+
+	if strings.Join(kinds, " ") != strings.Join(expected, " ") {
+		t.Errorf("wrong visiting order.\nwant=%v\ngot =%v", expected, kinds)
+	}
+}
+
+func TestInspectPrune(t *testing.T) {
+	program := parse(t, `let f = fn(a) { a }; b`)
+
+	var idents []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FunctionLiteral); ok {
+			return false
+		}
+		if ident, ok := n.(*ast.Identifier); ok {
+			idents = append(idents, ident.Value)
+		}
+		return true
+	})
+
+	if strings.Join(idents, ",") != "f,b" {
+		t.Errorf("wrong identifiers. want=f,b got=%s", strings.Join(idents, ","))
+	}
+}
+
+type depthVisitor struct {
+	depth    int
+	maxDepth *int
+}
+
+func (v depthVisitor) Visit(n ast.Node) ast.Visitor {
+	if n == nil {
+		return nil
+	}
+	if v.depth > *v.maxDepth {
+		*v.maxDepth = v.depth
+	}
+	return depthVisitor{depth: v.depth + 1, maxDepth: v.maxDepth}
+}
+
+func TestWalk(t *testing.T) {
+	program := parse(t, `1 + 2 * 3`)
+
+	maxDepth := 0
+	ast.Walk(depthVisitor{maxDepth: &maxDepth}, program)
+
+	// Program > ExpressionStatement > + > * > 2
+	if maxDepth != 4 {
+		t.Errorf("wrong max depth. want=4, got=%d", maxDepth)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2", "3"},
+		{"(1 + 2) * x", "(3 * x)"},
+		{"let f = fn(a) { return a + (2 + 2); }", "let f = fn(a) return (a + 4);;"},
+		{"add(1 + 1, 2 + 3)", "add(2, 5)"},
+		{"if (x) { 1 + 1 } else { 2 + 2 }", "ifx 2else 4"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+
+		// fold additions of integer literals
+		ast.Rewrite(program, func(n ast.Node) ast.Node {
+			infix, ok := n.(*ast.InfixExpression)
+			if !ok || infix.Operator != "+" {
+				return n
+			}
+			left, lok := infix.Left.(*ast.IntegerLiteral)
+			right, rok := infix.Right.(*ast.IntegerLiteral)
+			if !lok || !rok {
+				return n
+			}
+
+			sum := left.Value + right.Value
+			lit := &ast.IntegerLiteral{Token: left.Token, Value: sum}
+			lit.Token.Literal = fmt.Sprintf("%d", sum)
+			return lit
+		})
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong rewrite of %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestRewriteRemovesStatements(t *testing.T) {
+	program := parse(t, `let a = 1; 2; fn() { 3; let b = 4; }`)
+
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		if stmt, ok := n.(*ast.ExpressionStatement); ok {
+			if _, ok := stmt.Expression.(*ast.IntegerLiteral); ok {
+				return nil
+			}
+		}
+		return n
+	})
+
+	expected := "let a = 1;fn() let b = 4;"
+	if program.String() != expected {
+		t.Errorf("wrong program. want=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestRewritePanicsOnInvalidReplacement(t *testing.T) {
+	program := parse(t, `let a = 1;`)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected Rewrite to panic")
+		}
+	}()
+
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		if _, ok := n.(*ast.Identifier); ok {
+			return &ast.IntegerLiteral{}
+		}
+		return n
+	})
+}
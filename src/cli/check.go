@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/salty-max/lars/src/analysis"
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/parser"
+)
+
+const checkUsage = `usage: lars check [-format text|json|github] path ...
+
+Parses lars files without running them and runs the static analyses.
+Directories are walked for *.lars files. Exits 1 if any error is found;
+warnings alone do not fail the check.
+
+`
+
+// fileDiagnostic is a diagnostic along with the file it was found in.
+type fileDiagnostic struct {
+	File string
+	analysis.Diagnostic
+}
+
+// runCheck implements `lars check`.
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, checkUsage)
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output `format`: text, json or github")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	var write func(io.Writer, []fileDiagnostic) error
+	switch *format {
+	case "text":
+		write = writeTextDiagnostics
+	case "json":
+		write = writeJSONDiagnostics
+	case "github":
+		write = writeGitHubDiagnostics
+	default:
+		fmt.Fprintf(stderr, "lars check: unknown format %q\n", *format)
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	failed := false
+	var diags []fileDiagnostic
+	for _, path := range flags.Args() {
+		files, err := larsFiles(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			failed = true
+			continue
+		}
+
+		for _, file := range files {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				failed = true
+				continue
+			}
+
+			for _, d := range checkSource(string(src)) {
+				diags = append(diags, fileDiagnostic{File: file, Diagnostic: d})
+				if d.Severity == analysis.ERROR {
+					failed = true
+				}
+			}
+		}
+	}
+
+	if err := write(stdout, diags); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// checkSource parses src and, if it parses, analyses it.
+func checkSource(src string) []analysis.Diagnostic {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return analysis.FromParserErrors(p.Errors())
+	}
+
+	return analysis.Run(program)
+}
+
+func writeTextDiagnostics(w io.Writer, diags []fileDiagnostic) error {
+	for _, d := range diags {
+		_, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", d.File, d.Line, d.Col, d.Severity, d.Message)
+		if err != nil {
+			return err
+		}
+		if d.Hint != "" {
+			if _, err := fmt.Fprintf(w, "\thelp: %s\n", d.Hint); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeJSONDiagnostics(w io.Writer, diags []fileDiagnostic) error {
+	type jsonDiagnostic struct {
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+		Col      int    `json:"col"`
+		Severity string `json:"severity"`
+		Message  string `json:"message"`
+		Hint     string `json:"hint,omitempty"`
+		Source   string `json:"source"`
+	}
+
+	out := make([]jsonDiagnostic, 0, len(diags))
+	for _, d := range diags {
+		out = append(out, jsonDiagnostic{
+			File:     d.File,
+			Line:     d.Line,
+			Col:      d.Col,
+			Severity: d.Severity.String(),
+			Message:  d.Message,
+			Hint:     d.Hint,
+			Source:   d.Source,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeGitHubDiagnostics writes diagnostics as GitHub Actions workflow
+// commands, which show up as annotations on the pull request.
+func writeGitHubDiagnostics(w io.Writer, diags []fileDiagnostic) error {
+	for _, d := range diags {
+		msg := d.Message
+		if d.Hint != "" {
+			msg += "\nhelp: " + d.Hint
+		}
+
+		_, err := fmt.Fprintf(w, "::%s file=%s,line=%d,col=%d,title=%s::%s\n",
+			d.Severity,
+			escapeGitHubProperty(d.File),
+			d.Line,
+			d.Col,
+			escapeGitHubProperty("lars "+d.Source),
+			escapeGitHubData(msg),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ok.lars":      "let a = 1;\na + 1;\n",
+		"warn.lars":    "let a = 1;\nlet a = 2;\n",
+		"broken.lars":  "let = 1;\n",
+		"ignored.txt":  "let = 1;\n",
+		"sub/ok2.lars": "fn(x) { x }(1);\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	broken := filepath.Join(dir, "broken.lars")
+	warn := filepath.Join(dir, "warn.lars")
+
+	tests := []struct {
+		args           []string
+		expectedCode   int
+		expectedStdout []string
+	}{
+		{[]string{filepath.Join(dir, "ok.lars"), filepath.Join(dir, "sub")}, exitOK, nil},
+		{
+			[]string{warn},
+			exitOK,
+			[]string{warn + ":2:5: warning: a redeclared in this block (declared at 1:5)"},
+		},
+		{
+			[]string{dir},
+			exitFailure,
+			[]string{
+				broken + ":1:5: error: expected next token to be IDENT, got = instead",
+				warn + ":2:5: warning: a redeclared in this block (declared at 1:5)",
+			},
+		},
+		{
+			[]string{"-format", "github", broken},
+			exitFailure,
+			[]string{
+				"::error file=" + broken + ",line=1,col=5,title=lars parser::" +
+					"expected next token to be IDENT, got = instead",
+			},
+		},
+		{
+			[]string{"-format", "json", broken},
+			exitFailure,
+			[]string{
+				"[",
+				"  {",
+				`    "file": "` + broken + `",`,
+				`    "line": 1,`,
+				`    "col": 5,`,
+				`    "severity": "error",`,
+				`    "message": "expected next token to be IDENT, got = instead",`,
+				`    "source": "parser"`,
+				"  }",
+				"]",
+			},
+		},
+		{[]string{"-format", "xml", broken}, exitUsage, nil},
+		{nil, exitUsage, nil},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runCheck(tt.args, &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("check %v: wrong exit code. want=%d, got=%d", tt.args, tt.expectedCode, code)
+		}
+
+		expected := ""
+		if tt.expectedStdout != nil {
+			expected = strings.Join(tt.expectedStdout, "\n") + "\n"
+		}
+		if stdout.String() != expected {
+			t.Errorf("check %v: wrong output.\nwant=%q\ngot =%q", tt.args, expected, stdout.String())
+		}
+	}
+}
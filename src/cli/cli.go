@@ -0,0 +1,128 @@
+// Package cli implements the lars command-line driver.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/log"
+	"github.com/salty-max/lars/src/parser"
+	"github.com/salty-max/lars/src/repl"
+)
+
+// Exit codes of the lars command. A script that calls exit(n) exits with n
+// instead.
+const (
+	exitOK           = 0
+	exitFailure      = 1 // I/O errors, failed checks, or files that need formatting
+	exitUsage        = 2
+	exitParseError   = 3
+	exitRuntimeError = 4
+)
+
+const usage = `usage: lars [-color mode] [-e source] [-ast-json file] [file [args ...]]
+       lars run file [args ...]
+       lars fmt [-w | -d] [path ...]
+       lars check [-format text|json|github] path ...
+       lars tokens file
+       lars ast [-json] file
+
+Without a file or -e, lars starts the REPL.
+
+`
+
+// Main runs the lars command with args, not including the program name,
+// and returns the process exit code.
+func Main(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			return runCmd(args[1:], stdin, stdout, stderr)
+		case "fmt":
+			return runFmt(args[1:], stdin, stdout, stderr)
+		case "check":
+			return runCheck(args[1:], stdout, stderr)
+		case "tokens":
+			return runTokens(args[1:], stdout, stderr)
+		case "ast":
+			return runAST(args[1:], stdout, stderr)
+		}
+	}
+
+	flags := flag.NewFlagSet("lars", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	source := flags.String("e", "", "evaluate `source` and print its result")
+	astJSON := flags.String("ast-json", "", "parse `file` and print its AST as JSON")
+	color := flags.String("color", "auto", "color REPL output: `mode` is auto, always or never")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	colorMode, err := log.ParseColorMode(*color)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	switch {
+	case *astJSON != "":
+		return dumpASTJSON(*astJSON, stdout, stderr)
+	case *source != "":
+		return execute("-e", *source, flags.Args(), true, stdin, stdout, stderr)
+	case flags.NArg() > 0:
+		return runFile(flags.Arg(0), flags.Args()[1:], stdin, stdout, stderr)
+	}
+
+	user, err := user.Current()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	repl.Start(stdin, stdout, user, colorMode)
+	return exitOK
+}
+
+// dumpASTJSON parses the file at path and writes its AST to stdout as JSON.
+func dumpASTJSON(path string, stdout, stderr io.Writer) int {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(stderr, path, p.Errors())
+		return exitParseError
+	}
+
+	data, err := ast.MarshalJSON(program)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	fmt.Fprintf(stdout, "%s\n", data)
+	return exitOK
+}
+
+// printParserErrors writes errs to w as path:line:col: message lines.
+func printParserErrors(w io.Writer, path string, errs []parser.ParserError) {
+	for _, err := range errs {
+		fmt.Fprintf(w, "%s:%d:%d: %s\n", path, err.Line, err.Col, err.Msg)
+		if err.Hint != "" {
+			fmt.Fprintf(w, "\thelp: %s\n", err.Hint)
+		}
+	}
+}
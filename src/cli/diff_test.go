@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected []string
+	}{
+		{"a\nb\n", "a\nb\n", nil},
+		{
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			[]string{"--- x.orig", "+++ x", "@@ -1,3 +1,3 @@", " a", "-b", "+B", " c"},
+		},
+		{
+			"",
+			"a\n",
+			[]string{"--- x.orig", "+++ x", "@@ -0,0 +1 @@", "+a"},
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			[]string{
+				"--- x.orig", "+++ x",
+				"@@ -1,4 +1,4 @@", "-1", "+one", " 2", " 3", " 4",
+				"@@ -9,4 +9,4 @@", " 9", " 10", " 11", "-12", "+twelve",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		expected := ""
+		if tt.expected != nil {
+			expected = strings.Join(tt.expected, "\n") + "\n"
+		}
+
+		if got := unifiedDiff("x.orig", "x", tt.a, tt.b); got != expected {
+			t.Errorf("unifiedDiff(%q, %q) wrong.\nwant=%q\ngot =%q", tt.a, tt.b, expected, got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/parser"
+	"github.com/salty-max/lars/src/token"
+)
+
+// runTokens implements `lars tokens file`: it prints every token the lexer
+// produces for file, one per line.
+func runTokens(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: lars tokens file")
+		return exitUsage
+	}
+
+	src, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	l := lexer.New(string(src))
+	for {
+		tok := l.NextToken()
+		fmt.Fprintln(stdout, tok.Debug())
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	return exitOK
+}
+
+// runAST implements `lars ast [-json] file`: it prints the AST of file as
+// an indented tree with node kinds and spans, or as JSON.
+func runAST(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the AST as JSON")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: lars ast [-json] file")
+		return exitUsage
+	}
+	path := flags.Arg(0)
+
+	if *asJSON {
+		return dumpASTJSON(path, stdout, stderr)
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(stderr, path, p.Errors())
+		return exitParseError
+	}
+
+	if err := ast.Dump(stdout, program); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	return exitOK
+}
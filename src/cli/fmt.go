@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/salty-max/lars/src/format"
+)
+
+const fmtUsage = `usage: lars fmt [-w | -d] [path ...]
+
+Formats lars source files. Directories are walked for *.lars files. With
+no path, fmt formats standard input to standard output.
+
+`
+
+// runFmt implements `lars fmt`. It exits 1 if a file cannot be read or
+// parsed, or, with -d, if any file is not formatted.
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, fmtUsage)
+		flags.PrintDefaults()
+	}
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	diff := flags.Bool("d", false, "print a diff instead of the formatted source")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if *write && *diff {
+		fmt.Fprintln(stderr, "lars fmt: -w and -d are mutually exclusive")
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(stderr, "lars fmt: cannot use -w with standard input")
+			return exitUsage
+		}
+
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		if !formatFile("<stdin>", src, false, *diff, stdout, stderr) {
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	ok := true
+	for _, path := range flags.Args() {
+		files, err := larsFiles(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			ok = false
+			continue
+		}
+
+		for _, file := range files {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				ok = false
+				continue
+			}
+			if !formatFile(file, src, *write, *diff, stdout, stderr) {
+				ok = false
+			}
+		}
+	}
+
+	if !ok {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// formatFile formats src and writes the result according to the mode. It
+// reports false on error, or in diff mode if src was not formatted.
+func formatFile(path string, src []byte, write, diff bool, stdout, stderr io.Writer) bool {
+	out, err := format.Source(src)
+	if err != nil {
+		var syntaxErr *format.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			printParserErrors(stderr, path, syntaxErr.Errors)
+		} else {
+			fmt.Fprintf(stderr, "%s: %s\n", path, err)
+		}
+		return false
+	}
+
+	switch {
+	case write:
+		if bytes.Equal(src, out) {
+			return true
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return false
+		}
+		if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			fmt.Fprintln(stderr, err)
+			return false
+		}
+	case diff:
+		if bytes.Equal(src, out) {
+			return true
+		}
+		io.WriteString(stdout, unifiedDiff(path+".orig", path, string(src), string(out)))
+		return false
+	default:
+		stdout.Write(out)
+	}
+
+	return true
+}
+
+// larsFiles returns path itself if it is a file, or every *.lars file
+// below it if it is a directory.
+func larsFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(p) == ".lars" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/salty-max/lars/src/evaluator"
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+)
+
+// runCmd implements `lars run file [args ...]`.
+func runCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: lars run file [args ...]")
+		return exitUsage
+	}
+
+	return runFile(args[0], args[1:], stdin, stdout, stderr)
+}
+
+// runFile executes the script at path with scriptArgs bound to `args`.
+func runFile(path string, scriptArgs []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	return execute(path, string(src), scriptArgs, false, stdin, stdout, stderr)
+}
+
+// execute parses and evaluates src, reporting diagnostics against name. If
+// printResult is set, the value of the last statement is written to stdout.
+// It returns the exit code the program asked for, or one that reflects how
+// it failed. The script reads and prints to stdin, stdout and stderr.
+func execute(name, src string, scriptArgs []string, printResult bool, stdin io.Reader, stdout, stderr io.Writer) int {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(stderr, name, p.Errors())
+		return exitParseError
+	}
+
+	env := object.NewEnvironment()
+	env.SetStdout(stdout)
+	env.SetStderr(stderr)
+	env.SetStdin(stdin)
+	env.Set("args", scriptArguments(scriptArgs))
+
+	switch result := evaluator.EvalContext(context.Background(), program, env, evaluator.Limits{}).(type) {
+	case *object.Exit:
+		return result.Code
+	case *object.Error:
+		fmt.Fprintf(stderr, "%s:%d:%d: %s\n", name, result.Line, result.Col, result.Message)
+		if result.Hint != "" {
+			fmt.Fprintf(stderr, "\thelp: %s\n", result.Hint)
+		}
+		return exitRuntimeError
+	case nil, *object.Null:
+	default:
+		if printResult {
+			fmt.Fprintln(stdout, result.Inspect())
+		}
+	}
+
+	return exitOK
+}
+
+func scriptArguments(args []string) *object.Array {
+	elements := make([]object.Object, 0, len(args))
+	for _, arg := range args {
+		elements = append(elements, &object.String{Value: arg})
+	}
+	return &object.Array{Elements: elements}
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		input          string
+		args           []string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{"1 + 2", nil, exitOK, "3\n", ""},
+		{"let x = 1;", nil, exitOK, "", ""},
+		{"args[1]", []string{"a", "b"}, exitOK, "b\n", ""},
+		{"exit(42)", nil, 42, "", ""},
+		{"let = 1", nil, exitParseError, "", "-e:1:5: expected next token to be IDENT, got = instead\n"},
+		{"1 + true", nil, exitRuntimeError, "", "-e:1:3: type mismatch: INTEGER + BOOLEAN\n"},
+		{"let count = 1; cont", nil, exitRuntimeError, "",
+			"-e:1:16: identifier not found: cont\n\thelp: did you mean `count`?\n"},
+		{"let f = fn() { f() }; f()", nil, exitRuntimeError, "", "-e:1:17: maximum call depth exceeded (10000 calls)\n"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := execute("-e", tt.input, tt.args, true, nil, &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("input %q: wrong exit code. want=%d, got=%d", tt.input, tt.expectedCode, code)
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("input %q: wrong stdout. want=%q, got=%q", tt.input, tt.expectedStdout, stdout.String())
+		}
+		if stderr.String() != tt.expectedStderr {
+			t.Errorf("input %q: wrong stderr. want=%q, got=%q", tt.input, tt.expectedStderr, stderr.String())
+		}
+	}
+}
+
+func TestMainRunsScripts(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.lars")
+	src := "#!/usr/bin/env lars\nif (args[0] == \"ok\") { exit(0) } else { exit(9) }\n"
+	if err := os.WriteFile(script, []byte(src), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args         []string
+		expectedCode int
+	}{
+		{[]string{"run", script, "ok"}, 0},
+		{[]string{"run", script, "ko"}, 9},
+		{[]string{script, "ok"}, 0},
+		{[]string{script}, exitRuntimeError},
+		{[]string{"run"}, exitUsage},
+		{[]string{"run", script + ".missing"}, exitFailure},
+		{[]string{"-e", "exit(nope)"}, exitRuntimeError},
+		{[]string{"-e", "exit(5)"}, 5},
+		{[]string{"-bogus"}, exitUsage},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := Main(tt.args, nil, &stdout, &stderr); code != tt.expectedCode {
+			t.Errorf("lars %v: wrong exit code. want=%d, got=%d (stderr=%q)",
+				tt.args, tt.expectedCode, code, stderr.String())
+		}
+	}
+}
+
+func TestDumpCommands(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.lars")
+	if err := os.WriteFile(script, []byte("let x = 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(t.TempDir(), "broken.lars")
+	if err := os.WriteFile(broken, []byte("let = 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args           []string
+		expectedCode   int
+		expectedStdout string
+	}{
+		{[]string{"tokens", script}, exitOK,
+			"LET let (1:1)\nIDENT x (1:5)\n= = (1:7)\nINT 1 (1:9)\n; ; (1:10)\nEOF  (1:11)\n"},
+		{[]string{"ast", script}, exitOK,
+			"Program [1:1-1:9]\n  VarDeclStatement let [1:1-1:9]\n    Identifier x [1:5-1:5]\n    IntegerLiteral 1 [1:9-1:9]\n"},
+		{[]string{"ast", broken}, exitParseError, ""},
+		{[]string{"tokens"}, exitUsage, ""},
+		{[]string{"ast", script, script}, exitUsage, ""},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := Main(tt.args, nil, &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("args %v: wrong exit code. want=%d, got=%d", tt.args, tt.expectedCode, code)
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("args %v: wrong stdout. want=%q, got=%q", tt.args, tt.expectedStdout, stdout.String())
+		}
+	}
+}
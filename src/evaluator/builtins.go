@@ -0,0 +1,384 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/salty-max/lars/src/object"
+)
+
+var builtins = map[string]*object.Builtin{
+	"exit": {
+		Name: "exit",
+		Fn: func(_ *object.Environment, args ...object.Object) object.Object {
+			switch len(args) {
+			case 0:
+				return &object.Exit{Code: 0}
+			case 1:
+				code, ok := args[0].(*object.Integer)
+				if !ok {
+					return newBuiltinError("argument to `exit` must be INTEGER, got %s", args[0].Type())
+				}
+				if code.Value < 0 || code.Value > 255 {
+					return newBuiltinError("exit code must be between 0 and 255, got %d", code.Value)
+				}
+				return &object.Exit{Code: int(code.Value)}
+			default:
+				return newBuiltinError("wrong number of arguments: want=0 or 1, got=%d", len(args))
+			}
+		},
+	},
+	// len returns the number of characters in a string, elements in an
+	// array or pairs in a hash.
+	"len": {
+		Name: "len",
+		Fn: func(_ *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.String:
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Array:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(arg.Len())}
+			default:
+				return newBuiltinError("argument to `len` must be STRING, ARRAY or HASH, got %s", args[0].Type())
+			}
+		},
+	},
+	// str returns the text a value prints as.
+	"str": {
+		Name: "str",
+		Fn: func(_ *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+			}
+			if s, ok := args[0].(*object.String); ok {
+				return s
+			}
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+	// int truncates floats, parses decimal strings, and turns true and
+	// false into 1 and 0.
+	"int": {
+		Name: "int",
+		Fn: func(_ *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+			case *object.Float:
+				// float64(math.MaxInt64) rounds up to 2^63, which overflows
+				if math.IsNaN(arg.Value) || arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+					return newBuiltinError("cannot convert %s to INTEGER: out of range", arg.Inspect())
+				}
+				return &object.Integer{Value: int64(arg.Value)}
+			case *object.String:
+				i, err := strconv.ParseInt(arg.Value, 10, 64)
+				if err != nil {
+					return conversionError(arg, object.INTEGER_OBJ, err)
+				}
+				return &object.Integer{Value: i}
+			case *object.Boolean:
+				if arg.Value {
+					return &object.Integer{Value: 1}
+				}
+				return &object.Integer{Value: 0}
+			default:
+				return newBuiltinError("argument to `int` must be INTEGER, FLOAT, STRING or BOOLEAN, got %s", args[0].Type())
+			}
+		},
+	},
+	// float converts integers, parses strings, and turns true and false
+	// into 1.0 and 0.0.
+	"float": {
+		Name: "float",
+		Fn: func(_ *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return &object.Float{Value: float64(arg.Value)}
+			case *object.Float:
+				return arg
+			case *object.String:
+				f, err := strconv.ParseFloat(arg.Value, 64)
+				if err != nil {
+					return conversionError(arg, object.FLOAT_OBJ, err)
+				}
+				return &object.Float{Value: f}
+			case *object.Boolean:
+				if arg.Value {
+					return &object.Float{Value: 1}
+				}
+				return &object.Float{Value: 0}
+			default:
+				return newBuiltinError("argument to `float` must be INTEGER, FLOAT, STRING or BOOLEAN, got %s", args[0].Type())
+			}
+		},
+	},
+	// bool is false for zero, null and the string "false", and true for
+	// other numbers and the string "true". Unlike a condition, it does not
+	// treat 0 as true, and rejects other strings.
+	"bool": {
+		Name: "bool",
+		Fn: func(_ *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Boolean:
+				return arg
+			case *object.Integer:
+				return nativeBoolToBooleanObject(arg.Value != 0)
+			case *object.Float:
+				return nativeBoolToBooleanObject(arg.Value != 0)
+			case *object.String:
+				switch arg.Value {
+				case "true":
+					return TRUE
+				case "false":
+					return FALSE
+				}
+				return newBuiltinError("cannot convert %q to BOOLEAN", arg.Value)
+			case *object.Null:
+				return FALSE
+			default:
+				return newBuiltinError("argument to `bool` must be INTEGER, FLOAT, STRING, BOOLEAN or NULL, got %s", args[0].Type())
+			}
+		},
+	},
+	// type returns the type of a value, such as "INTEGER".
+	"type": {
+		Name: "type",
+		Fn: func(_ *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+			}
+			return &object.String{Value: string(args[0].Type())}
+		},
+	},
+	// assert fails with msg, if given, unless cond is truthy.
+	"assert": {
+		Name: "assert",
+		Fn: func(_ *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newBuiltinError("wrong number of arguments: want=1 or 2, got=%d", len(args))
+			}
+			msg := ""
+			if len(args) == 2 {
+				s, ok := args[1].(*object.String)
+				if !ok {
+					return newBuiltinError("second argument to `assert` must be STRING, got %s", args[1].Type())
+				}
+				msg = s.Value
+			}
+
+			if isTruthy(args[0]) {
+				return NULL
+			}
+			if msg == "" {
+				return newBuiltinError("assertion failed")
+			}
+			return newBuiltinError("assertion failed: %s", msg)
+		},
+	},
+	// The builtins below have side effects, and need a capability when
+	// the code calling them is sandboxed.
+	//
+	// print writes its arguments to the standard output of the script,
+	// separated by spaces; puts also ends the line, and eputs is puts for
+	// standard error.
+	"print": {
+		Name: "print",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return write(env, "print", object.CapStdout, env.Stdout(), args, "")
+		},
+	},
+	"puts": {
+		Name: "puts",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return write(env, "puts", object.CapStdout, env.Stdout(), args, "\n")
+		},
+	},
+	"eputs": {
+		Name: "eputs",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return write(env, "eputs", object.CapStderr, env.Stderr(), args, "\n")
+		},
+	},
+	// input prints prompt, if given, and reads a line of standard input,
+	// without its line ending. It returns null at the end of the input.
+	"input": {
+		Name: "input",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newBuiltinError("wrong number of arguments: want=0 or 1, got=%d", len(args))
+			}
+			if err := permit(env, "input", object.CapStdin, ""); err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				if _, ok := args[0].(*object.String); !ok {
+					return newBuiltinError("argument to `input` must be STRING, got %s", args[0].Type())
+				}
+				if err := write(env, "input", object.CapStdout, env.Stdout(), args, ""); isError(err) {
+					return err
+				}
+			}
+
+			line, err := env.Stdin().ReadString('\n')
+			if err == io.EOF && line == "" {
+				return NULL
+			}
+			if err != nil && err != io.EOF {
+				return &object.Error{Message: "input: " + err.Error(), Err: err}
+			}
+			line = strings.TrimSuffix(line, "\n")
+			return &object.String{Value: strings.TrimSuffix(line, "\r")}
+		},
+	},
+	"read_file": {
+		Name: "read_file",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+			}
+			path, ok := args[0].(*object.String)
+			if !ok {
+				return newBuiltinError("argument to `read_file` must be STRING, got %s", args[0].Type())
+			}
+			if err := permit(env, "read_file", object.CapFSRead, path.Value); err != nil {
+				return err
+			}
+
+			data, err := os.ReadFile(path.Value)
+			if err != nil {
+				return &object.Error{Message: "read_file: " + err.Error(), Err: err}
+			}
+			return &object.String{Value: string(data)}
+		},
+	},
+	// getenv returns null for unset variables.
+	"getenv": {
+		Name: "getenv",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+			}
+			name, ok := args[0].(*object.String)
+			if !ok {
+				return newBuiltinError("argument to `getenv` must be STRING, got %s", args[0].Type())
+			}
+			if err := permit(env, "getenv", object.CapEnv, name.Value); err != nil {
+				return err
+			}
+
+			value, ok := os.LookupEnv(name.Value)
+			if !ok {
+				return NULL
+			}
+			return &object.String{Value: value}
+		},
+	},
+	// now returns the time in milliseconds since the Unix epoch.
+	"now": {
+		Name: "now",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newBuiltinError("wrong number of arguments: want=0, got=%d", len(args))
+			}
+			if err := permit(env, "now", object.CapClock, ""); err != nil {
+				return err
+			}
+
+			return &object.Integer{Value: time.Now().UnixMilli()}
+		},
+	},
+	// random returns a float in [0, 1).
+	"random": {
+		Name: "random",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newBuiltinError("wrong number of arguments: want=0, got=%d", len(args))
+			}
+			if err := permit(env, "random", object.CapRandom, ""); err != nil {
+				return err
+			}
+
+			return &object.Float{Value: rand.Float64()}
+		},
+	},
+}
+
+// BuiltinNames returns the names of the builtins, sorted.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// permit checks that the code running in env was granted the capability
+// name on resource, which builtin needs. It returns a permission error if
+// not, and nil otherwise.
+func permit(env *object.Environment, builtin, name, resource string) *object.Error {
+	if env.Grants().Allows(name, resource) {
+		return nil
+	}
+
+	err := &object.PermissionError{
+		Builtin:    builtin,
+		Capability: object.Capability{Name: name, Scope: resource},
+	}
+	return &object.Error{Message: err.Error(), Err: err}
+}
+
+// write writes args to w, the standard output or error of env, for
+// builtin, which needs the capability name. The args are separated by
+// spaces and followed by end.
+func write(env *object.Environment, builtin, name string, w io.Writer, args []object.Object, end string) object.Object {
+	if err := permit(env, builtin, name, ""); err != nil {
+		return err
+	}
+
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.Inspect()
+	}
+	if _, err := io.WriteString(w, strings.Join(parts, " ")+end); err != nil {
+		return &object.Error{Message: builtin + ": " + err.Error(), Err: err}
+	}
+	return NULL
+}
+
+// conversionError reports that s cannot be parsed as a value of type t.
+func conversionError(s *object.String, t object.ObjectType, err error) *object.Error {
+	if errors.Is(err, strconv.ErrRange) {
+		return newBuiltinError("cannot convert %q to %s: out of range", s.Value, t)
+	}
+	return newBuiltinError("cannot convert %q to %s", s.Value, t)
+}
+
+// newBuiltinError creates an error without a position; applyFunction
+// reports it at the call site.
+func newBuiltinError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,218 @@
+package evaluator
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+)
+
+func testEvalGranted(input string, grants *object.Grants) object.Object {
+	env := object.NewEnvironment()
+	env.SetGrants(grants)
+	return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+}
+
+func TestCoreBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len("")`, "0"},
+		{`len("héllo")`, "5"},
+		{"len([1, 2, 3])", "3"},
+		{`len({"a": 1, "b": 2})`, "2"},
+		{"str(12)", "12"},
+		{"str([1, true])", "[1, true]"},
+		{`str("s") + "!"`, "s!"},
+		{"int(7)", "7"},
+		{"int(3.9)", "3"},
+		{"int(-3.9)", "-3"},
+		{`int("-42")`, "-42"},
+		{"int(true) + int(false)", "1"},
+		{"float(2)", "2.000000"},
+		{`float("2.5")`, "2.500000"},
+		{"float(true)", "1.000000"},
+		{"bool(0)", "false"},
+		{"bool(0.5)", "true"},
+		{`bool("false")`, "false"},
+		{`bool("true")`, "true"},
+		{"bool(null)", "false"},
+		{"type(1)", "INTEGER"},
+		{"type(len)", "BUILTIN"},
+		{`type({})`, "HASH"},
+		{"assert(1 < 2)", "null"},
+		{`let len = fn(x) { 42 }; len("shadowed")`, "42"},
+
+		{"len(1)", "Error (1:4) -> argument to `len` must be STRING, ARRAY or HASH, got INTEGER"},
+		{`len("a", "b")`, "Error (1:4) -> wrong number of arguments: want=1, got=2"},
+		{"str()", "Error (1:4) -> wrong number of arguments: want=1, got=0"},
+		{`int("12abc")`, `Error (1:4) -> cannot convert "12abc" to INTEGER`},
+		{`int("99999999999999999999")`, `Error (1:4) -> cannot convert "99999999999999999999" to INTEGER: out of range`},
+		{"int(10000000000.0 * 10000000000.0)", "Error (1:4) -> cannot convert 100000000000000000000.000000 to INTEGER: out of range"},
+		{"int(null)", "Error (1:4) -> argument to `int` must be INTEGER, FLOAT, STRING or BOOLEAN, got NULL"},
+		{`float("pi")`, `Error (1:6) -> cannot convert "pi" to FLOAT`},
+		{"float([])", "Error (1:6) -> argument to `float` must be INTEGER, FLOAT, STRING or BOOLEAN, got ARRAY"},
+		{`bool("yes")`, `Error (1:5) -> cannot convert "yes" to BOOLEAN`},
+		{"bool([])", "Error (1:5) -> argument to `bool` must be INTEGER, FLOAT, STRING, BOOLEAN or NULL, got ARRAY"},
+		{"type()", "Error (1:5) -> wrong number of arguments: want=1, got=0"},
+		{"let x = 1;\nassert(x == 2)", "Error (2:7) -> assertion failed"},
+		{`assert(false, "x must be 2")`, "Error (1:7) -> assertion failed: x must be 2"},
+		{"assert(false, 2)", "Error (1:7) -> second argument to `assert` must be STRING, got INTEGER"},
+		{"assert()", "Error (1:7) -> wrong number of arguments: want=1 or 2, got=0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`print("a", 1, [true])`, "a 1 [true]"},
+		{`print("no newline"); print("!")`, "no newline!"},
+		{`puts("a", 1)`, "a 1\n"},
+		{"puts()", "\n"},
+		{`let greet = fn(name) { puts("hello", name) }; greet("lars"); greet("go")`, "hello lars\nhello go\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		env := object.NewEnvironment()
+		env.SetStdout(&out)
+
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+		if evaluated != NULL {
+			t.Errorf("input %q: want=null, got=%s", tt.input, evaluated.Inspect())
+		}
+		if out.String() != tt.expected {
+			t.Errorf("input %q: wrong output. want=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+
+	evaluated := testEvalGranted(`puts("hi")`, object.NewGrants())
+	if want := "Error (1:5) -> permission denied: `puts` needs the stdout capability"; evaluated.Inspect() != want {
+		t.Errorf("want=%q, got=%q", want, evaluated.Inspect())
+	}
+}
+
+func TestInput(t *testing.T) {
+	var out, errOut bytes.Buffer
+	env := object.NewEnvironment()
+	env.SetStdout(&out)
+	env.SetStderr(&errOut)
+	env.SetStdin(strings.NewReader("first\r\nsecond"))
+
+	input := `[input(), input("name? "), input(), eputs("read", 2)]`
+	evaluated := Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	if want := "[first, second, null, null]"; evaluated.Inspect() != want {
+		t.Errorf("want=%q, got=%q", want, evaluated.Inspect())
+	}
+	if out.String() != "name? " {
+		t.Errorf("wrong stdout %q", out.String())
+	}
+	if errOut.String() != "read 2\n" {
+		t.Errorf("wrong stderr %q", errOut.String())
+	}
+
+	for input, want := range map[string]string{
+		"input(1)":        "Error (1:6) -> argument to `input` must be STRING, got INTEGER",
+		`input("a", "b")`: "Error (1:6) -> wrong number of arguments: want=0 or 1, got=2",
+	} {
+		if evaluated := testEval(input); evaluated.Inspect() != want {
+			t.Errorf("input %q: want=%q, got=%q", input, want, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSideEffectBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.txt")
+	if err := os.WriteFile(path, []byte("allow all"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LARS_TEST_VAR", "on")
+
+	grants, err := object.ParseGrants("fs.read:"+dir, "env:LARS_TEST_VAR", "clock", "random")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`read_file("` + path + `")`, "allow all"},
+		{`getenv("LARS_TEST_VAR")`, "on"},
+		{`now() > 1700000000000`, "true"},
+		{`let r = random(); if (r < 0) { false } else { r < 1 }`, "true"},
+		{`read_file(1)`, "Error (1:10) -> argument to `read_file` must be STRING, got INTEGER"},
+		{`now(1)`, "Error (1:4) -> wrong number of arguments: want=0, got=1"},
+	}
+
+	for _, tt := range tests {
+		for _, g := range []*object.Grants{nil, grants} {
+			evaluated := testEvalGranted(tt.input, g)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("input %q with grants %v: want=%q, got=%q",
+					tt.input, g.Capabilities(), tt.expected, evaluated.Inspect())
+			}
+		}
+	}
+
+	evaluated := testEvalGranted(`read_file("`+filepath.Join(dir, "missing")+`")`, grants)
+	if errObj, ok := evaluated.(*object.Error); !ok || !errors.Is(errObj.Err, fs.ErrNotExist) {
+		t.Errorf("reading a missing file: want a not-exist error, got %s", evaluated.Inspect())
+	}
+}
+
+func TestPermissionDenied(t *testing.T) {
+	grants, err := object.ParseGrants("fs.read:/data", "env:HOME")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := object.NewGrants(object.Capability{Name: object.CapStdin})
+
+	tests := []struct {
+		input    string
+		grants   *object.Grants
+		expected string
+	}{
+		{`read_file("/etc/passwd")`, grants, "permission denied: `read_file` needs the fs.read:/etc/passwd capability"},
+		{`getenv("PATH")`, grants, "permission denied: `getenv` needs the env:PATH capability"},
+		{"now()", grants, "permission denied: `now` needs the clock capability"},
+		{"random()", object.NewGrants(), "permission denied: `random` needs the random capability"},
+		{"input()", object.NewGrants(), "permission denied: `input` needs the stdin capability"},
+		{`input("> ")`, stdin, "permission denied: `input` needs the stdout capability"},
+		{`eputs("oops")`, object.NewGrants(), "permission denied: `eputs` needs the stderr capability"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalGranted(tt.input, tt.grants)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: no error object returned. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+		var permErr *object.PermissionError
+		if !errors.As(errObj.Err, &permErr) || !errors.Is(errObj.Err, fs.ErrPermission) {
+			t.Errorf("input %q: error does not wrap a PermissionError: %v", tt.input, errObj.Err)
+		}
+	}
+}
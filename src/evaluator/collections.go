@@ -0,0 +1,602 @@
+package evaluator
+
+import (
+	"cmp"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/token"
+)
+
+// The collection builtins work on arrays, and on host objects that
+// implement object.Iterable. They never modify their arguments. They are
+// registered in init because the lars functions they call back into can
+// refer to any builtin.
+func init() {
+	for _, b := range []*object.Builtin{
+		{Name: "map", Fn: builtinMap},
+		{Name: "filter", Fn: builtinFilter},
+		{Name: "reduce", Fn: builtinReduce},
+		{Name: "sort", Fn: builtinSort},
+		{Name: "zip", Fn: builtinZip},
+		{Name: "enumerate", Fn: builtinEnumerate},
+		{Name: "flatten", Fn: builtinFlatten},
+		{Name: "reverse", Fn: builtinReverse},
+		{Name: "unique", Fn: builtinUnique},
+		{Name: "chunk", Fn: builtinChunk},
+		{Name: "any", Fn: builtinAny},
+		{Name: "all", Fn: builtinAll},
+		{Name: "sum", Fn: builtinSum},
+		{Name: "min", Fn: builtinMin},
+		{Name: "max", Fn: builtinMax},
+	} {
+		builtins[b.Name] = b
+	}
+}
+
+// map(arr, f) returns the results of f(x) for each element x.
+func builtinMap(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newBuiltinError("wrong number of arguments: want=2, got=%d", len(args))
+	}
+	elements, errObj := iterate("map", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+	if errObj := callable("map", 2, args[1]); errObj != nil {
+		return errObj
+	}
+
+	result := make([]object.Object, len(elements))
+	for i, el := range elements {
+		value := callBack(env, args[1], el)
+		if isError(value) {
+			return value
+		}
+		result[i] = value
+	}
+	return &object.Array{Elements: result}
+}
+
+// filter(arr, pred) returns the elements x for which pred(x) is truthy.
+func builtinFilter(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newBuiltinError("wrong number of arguments: want=2, got=%d", len(args))
+	}
+	elements, errObj := iterate("filter", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+	if errObj := callable("filter", 2, args[1]); errObj != nil {
+		return errObj
+	}
+
+	result := []object.Object{}
+	for _, el := range elements {
+		keep := callBack(env, args[1], el)
+		if isError(keep) {
+			return keep
+		}
+		if isTruthy(keep) {
+			result = append(result, el)
+		}
+	}
+	return &object.Array{Elements: result}
+}
+
+// reduce(arr, f, initial) folds the elements into f(f(initial, x0), x1)
+// and so on. Without initial, the first element is the initial value.
+func builtinReduce(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newBuiltinError("wrong number of arguments: want=2 or 3, got=%d", len(args))
+	}
+	elements, errObj := iterate("reduce", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+	if errObj := callable("reduce", 2, args[1]); errObj != nil {
+		return errObj
+	}
+
+	var acc object.Object
+	switch {
+	case len(args) == 3:
+		acc = args[2]
+	case len(elements) == 0:
+		return newBuiltinError("reduce of an empty array with no initial value")
+	default:
+		acc, elements = elements[0], elements[1:]
+	}
+
+	for _, el := range elements {
+		acc = callBack(env, args[1], acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// sort(arr, f) returns the elements in increasing order. The sort is
+// stable. Without f, elements are ordered by compare. A function f of two
+// parameters is a comparator, returning a negative, zero or positive
+// INTEGER, or whether its first argument goes first. A BOOLEAN comparator
+// must be a strict "less than", false for equal elements, or the sort is
+// not stable. Any other function is a key, and elements are ordered by
+// compare on f(x).
+func builtinSort(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newBuiltinError("wrong number of arguments: want=1 or 2, got=%d", len(args))
+	}
+	elements, errObj := iterate("sort", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	sorted := append([]object.Object(nil), elements...)
+	if len(args) == 1 {
+		return sortBy(sorted, sorted)
+	}
+
+	f := args[1]
+	if errObj := callable("sort", 2, f); errObj != nil {
+		return errObj
+	}
+	if fn, ok := f.(*object.Function); ok && len(fn.Parameters) == 2 {
+		return sortWith(env, f, sorted)
+	}
+
+	keys := make([]object.Object, len(sorted))
+	for i, el := range sorted {
+		key := callBack(env, f, el)
+		if isError(key) {
+			return key
+		}
+		keys[i] = key
+	}
+	return sortBy(sorted, keys)
+}
+
+// sortBy sorts elements in place by compare on their keys, and returns
+// them as an array.
+func sortBy(elements, keys []object.Object) object.Object {
+	type keyed struct{ key, el object.Object }
+	pairs := make([]keyed, len(elements))
+	for i := range elements {
+		pairs[i] = keyed{keys[i], elements[i]}
+	}
+
+	var errObj *object.Error
+	sort.SliceStable(pairs, func(i, j int) bool {
+		c, err := compare(pairs[i].key, pairs[j].key)
+		if err != nil && errObj == nil {
+			errObj = err
+		}
+		return c < 0
+	})
+	if errObj != nil {
+		return errObj
+	}
+
+	for i, pair := range pairs {
+		elements[i] = pair.el
+	}
+	return &object.Array{Elements: elements}
+}
+
+// sortWith sorts elements in place with the comparator f, and returns them
+// as an array.
+func sortWith(env *object.Environment, f object.Object, elements []object.Object) object.Object {
+	var failed object.Object
+	sort.SliceStable(elements, func(i, j int) bool {
+		if failed != nil {
+			return false
+		}
+
+		switch c := callBack(env, f, elements[i], elements[j]).(type) {
+		case *object.Integer:
+			return c.Value < 0
+		case *object.Boolean:
+			return c.Value
+		default:
+			if isError(c) {
+				failed = c
+			} else {
+				failed = newBuiltinError("comparator passed to `sort` must return INTEGER or BOOLEAN, got %s", c.Type())
+			}
+			return false
+		}
+	})
+	if failed != nil {
+		return failed
+	}
+	return &object.Array{Elements: elements}
+}
+
+// zip(a, b, ...) returns arrays of the elements at the same position in
+// each argument, as long as the shortest one.
+func builtinZip(_ *object.Environment, args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newBuiltinError("wrong number of arguments: want at least 1, got=0")
+	}
+
+	arrays := make([][]object.Object, len(args))
+	length := -1
+	for n, arg := range args {
+		elements, errObj := iterate("zip", n+1, arg)
+		if errObj != nil {
+			return errObj
+		}
+		arrays[n] = elements
+		if length < 0 || len(elements) < length {
+			length = len(elements)
+		}
+	}
+
+	result := make([]object.Object, length)
+	for i := range result {
+		tuple := make([]object.Object, len(arrays))
+		for n, elements := range arrays {
+			tuple[n] = elements[i]
+		}
+		result[i] = &object.Array{Elements: tuple}
+	}
+	return &object.Array{Elements: result}
+}
+
+// enumerate(arr) returns [index, element] pairs.
+func builtinEnumerate(_ *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	elements, errObj := iterate("enumerate", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	result := make([]object.Object, len(elements))
+	for i, el := range elements {
+		result[i] = &object.Array{Elements: []object.Object{&object.Integer{Value: int64(i)}, el}}
+	}
+	return &object.Array{Elements: result}
+}
+
+// flatten(arr, depth) splices the elements of nested arrays into their
+// parent, depth levels deep; one level if depth is omitted.
+func builtinFlatten(_ *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newBuiltinError("wrong number of arguments: want=1 or 2, got=%d", len(args))
+	}
+	elements, errObj := iterate("flatten", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	depth := int64(1)
+	if len(args) == 2 {
+		d, ok := args[1].(*object.Integer)
+		if !ok {
+			return newBuiltinError("argument 2 to `flatten` must be INTEGER, got %s", args[1].Type())
+		}
+		if d.Value < 0 {
+			return newBuiltinError("flatten depth must not be negative, got %d", d.Value)
+		}
+		depth = d.Value
+	}
+
+	return &object.Array{Elements: flatten(nil, elements, depth)}
+}
+
+func flatten(dst, elements []object.Object, depth int64) []object.Object {
+	for _, el := range elements {
+		if nested, ok := el.(*object.Array); ok && depth > 0 {
+			dst = flatten(dst, nested.Elements, depth-1)
+		} else {
+			dst = append(dst, el)
+		}
+	}
+	if dst == nil {
+		return []object.Object{}
+	}
+	return dst
+}
+
+// reverse(arr) returns the elements in reverse order.
+func builtinReverse(_ *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	elements, errObj := iterate("reverse", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	result := make([]object.Object, len(elements))
+	for i, el := range elements {
+		result[len(elements)-1-i] = el
+	}
+	return &object.Array{Elements: result}
+}
+
+// unique(arr) returns the elements without repeats, in the order they
+// first appear. The elements must be usable as hash keys, or floats.
+// Numbers repeat when they are ==, so 1 and 1.0 are the same value.
+func builtinUnique(_ *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	elements, errObj := iterate("unique", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	seen := map[object.HashKey]bool{}
+	result := []object.Object{}
+	for _, el := range elements {
+		key, ok := uniqueKey(el)
+		if !ok {
+			return newBuiltinError("unusable as hash key: %s", el.Type())
+		}
+		if f, isFloat := el.(*object.Float); isFloat && math.IsNaN(f.Value) {
+			// NaN is not == to itself
+			result = append(result, el)
+		} else if !seen[key] {
+			seen[key] = true
+			result = append(result, el)
+		}
+	}
+	return &object.Array{Elements: result}
+}
+
+// uniqueKey returns the key unique tells el apart by. Floats with an
+// integer value share the key of the integer.
+func uniqueKey(el object.Object) (object.HashKey, bool) {
+	if f, ok := el.(*object.Float); ok {
+		if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+			return (&object.Integer{Value: int64(f.Value)}).HashKey(), true
+		}
+		return object.HashKey{Type: object.FLOAT_OBJ, Value: math.Float64bits(f.Value)}, true
+	}
+	hashable, ok := el.(object.Hashable)
+	if !ok {
+		return object.HashKey{}, false
+	}
+	return hashable.HashKey(), true
+}
+
+// chunk(arr, size) splits the elements into arrays of size elements; the
+// last one may be shorter.
+func builtinChunk(_ *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newBuiltinError("wrong number of arguments: want=2, got=%d", len(args))
+	}
+	elements, errObj := iterate("chunk", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+	size, ok := args[1].(*object.Integer)
+	if !ok {
+		return newBuiltinError("argument 2 to `chunk` must be INTEGER, got %s", args[1].Type())
+	}
+	if size.Value <= 0 {
+		return newBuiltinError("chunk size must be positive, got %d", size.Value)
+	}
+
+	result := []object.Object{}
+	for start := 0; start < len(elements); start += int(size.Value) {
+		end := min(start+int(size.Value), len(elements))
+		result = append(result, &object.Array{Elements: append([]object.Object(nil), elements[start:end]...)})
+	}
+	return &object.Array{Elements: result}
+}
+
+// any(arr, pred) reports whether pred(x) is truthy for some element, or
+// whether some element is truthy if pred is omitted.
+func builtinAny(env *object.Environment, args ...object.Object) object.Object {
+	return quantify(env, "any", true, args)
+}
+
+// all(arr, pred) reports whether pred(x) is truthy for every element, or
+// whether every element is truthy if pred is omitted.
+func builtinAll(env *object.Environment, args ...object.Object) object.Object {
+	return quantify(env, "all", false, args)
+}
+
+// quantify implements any, which stops at the first truthy test, and all,
+// which stops at the first falsy one.
+func quantify(env *object.Environment, builtin string, stopAt bool, args []object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newBuiltinError("wrong number of arguments: want=1 or 2, got=%d", len(args))
+	}
+	elements, errObj := iterate(builtin, 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+	if len(args) == 2 {
+		if errObj := callable(builtin, 2, args[1]); errObj != nil {
+			return errObj
+		}
+	}
+
+	for _, el := range elements {
+		test := el
+		if len(args) == 2 {
+			test = callBack(env, args[1], el)
+			if isError(test) {
+				return test
+			}
+		}
+		if isTruthy(test) == stopAt {
+			return nativeBoolToBooleanObject(stopAt)
+		}
+	}
+	return nativeBoolToBooleanObject(!stopAt)
+}
+
+// sum(arr) adds up numbers. The sum is an INTEGER if they all are, and a
+// FLOAT otherwise; it is 0 for an empty array. Unlike +, which wraps
+// around, an INTEGER sum fails if it overflows along the way.
+func builtinSum(_ *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newBuiltinError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	elements, errObj := iterate("sum", 1, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	var ints int64
+	var floats float64 // and the integers that would overflow ints
+	isFloat, overflow := false, false
+	for i, el := range elements {
+		switch el := el.(type) {
+		case *object.Integer:
+			if sum, ok := addInt(ints, el.Value); ok && !overflow {
+				ints = sum
+			} else {
+				floats += float64(el.Value)
+				overflow = true
+			}
+		case *object.Float:
+			floats += el.Value
+			isFloat = true
+		default:
+			return newBuiltinError("argument to `sum` must only hold INTEGER and FLOAT, got %s at [%d]", el.Type(), i)
+		}
+	}
+
+	if isFloat {
+		return &object.Float{Value: float64(ints) + floats}
+	}
+	if overflow {
+		return newBuiltinError("`sum` overflows INTEGER")
+	}
+	return &object.Integer{Value: ints}
+}
+
+// addInt returns a + b, and whether it did not overflow.
+func addInt(a, b int64) (int64, bool) {
+	sum := a + b
+	return sum, (sum > a) == (b > 0)
+}
+
+// min(arr) returns the smallest element by compare. It can also be called
+// with the values to compare as arguments, as in min(a, b).
+func builtinMin(_ *object.Environment, args ...object.Object) object.Object {
+	return extreme("min", -1, args)
+}
+
+// max(arr) returns the largest element by compare. It can also be called
+// with the values to compare as arguments, as in max(a, b).
+func builtinMax(_ *object.Environment, args ...object.Object) object.Object {
+	return extreme("max", 1, args)
+}
+
+// extreme implements min, with sign -1, and max, with sign 1. Of equal
+// elements, it returns the first.
+func extreme(builtin string, sign int, args []object.Object) object.Object {
+	elements := args
+	switch len(args) {
+	case 0:
+		return newBuiltinError("wrong number of arguments: want at least 1, got=0")
+	case 1:
+		var errObj *object.Error
+		if elements, errObj = iterate(builtin, 1, args[0]); errObj != nil {
+			return errObj
+		}
+		if len(elements) == 0 {
+			return newBuiltinError("`%s` of an empty array", builtin)
+		}
+	}
+
+	best := elements[0]
+	for _, el := range elements[1:] {
+		c, errObj := compare(el, best)
+		if errObj != nil {
+			return errObj
+		}
+		if c == sign {
+			best = el
+		}
+	}
+	return best
+}
+
+// compare orders a and b for sort, min and max: numbers by value, whether
+// they are integers or floats, and strings byte-wise. Other values cannot
+// be ordered, nor can numbers and strings be ordered together.
+func compare(a, b object.Object) (int, *object.Error) {
+	switch a := a.(type) {
+	case *object.Integer:
+		switch b := b.(type) {
+		case *object.Integer:
+			return cmp.Compare(a.Value, b.Value), nil
+		case *object.Float:
+			return cmp.Compare(float64(a.Value), b.Value), nil
+		}
+	case *object.Float:
+		switch b := b.(type) {
+		case *object.Integer:
+			return cmp.Compare(a.Value, float64(b.Value)), nil
+		case *object.Float:
+			return cmp.Compare(a.Value, b.Value), nil
+		}
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			return strings.Compare(a.Value, b.Value), nil
+		}
+	}
+	return 0, newBuiltinError("cannot compare %s and %s", a.Type(), b.Type())
+}
+
+// iterate returns the elements of arg, argument n of builtin: an array, or
+// a host object that implements object.Iterable.
+func iterate(builtin string, n int, arg object.Object) ([]object.Object, *object.Error) {
+	switch arg := arg.(type) {
+	case *object.Array:
+		return arg.Elements, nil
+	case object.Iterable:
+		var elements []object.Object
+		err := arg.Iterate(func(el object.Object) bool {
+			elements = append(elements, el)
+			return true
+		})
+		if err != nil {
+			e := newBuiltinError("%s: %s", arg.Type(), err)
+			e.Err = err
+			return nil, e
+		}
+		return elements, nil
+	}
+	return nil, argumentError(builtin, n, "ARRAY", arg)
+}
+
+// callable checks that arg, argument n of builtin, is a function.
+func callable(builtin string, n int, arg object.Object) *object.Error {
+	switch arg.(type) {
+	case *object.Function, *object.Builtin:
+		return nil
+	}
+	return argumentError(builtin, n, "FUNCTION", arg)
+}
+
+func argumentError(builtin string, n int, want string, got object.Object) *object.Error {
+	return newBuiltinError("argument %d to `%s` must be %s, got %s", n, builtin, want, got.Type())
+}
+
+// callBack calls fn, a function passed to a builtin that runs in env, with
+// args. Each call counts as a step against the limits of env, so that
+// builtins looping over large arrays can be stopped.
+func callBack(env *object.Environment, fn object.Object, args ...object.Object) object.Object {
+	if limiter := env.Limiter(); limiter != nil {
+		if err := limiter.Step(); err != nil {
+			return limitError(token.Token{}, err)
+		}
+	}
+	if result := applyFunction(token.Token{}, env, fn, args); result != nil {
+		return result
+	}
+	return NULL
+}
@@ -0,0 +1,171 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+)
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], fn(x) { x * x })", "[1, 4, 9]"},
+		{"map([], fn(x) { x })", "[]"},
+		{"map([1, 2], str)", "[1, 2]"},
+		{"map([1], fn(x) { })", "[null]"},
+		{"let k = 10; map([1, 2], fn(x) { x + k })", "[11, 12]"},
+		{"filter([1, 2, 3, 4], fn(x) { x % 2 == 0 })", "[2, 4]"},
+		{"filter([1, null, false, 0], fn(x) { x })", "[1, 0]"},
+		{"reduce([1, 2, 3, 4], fn(acc, x) { acc + x })", "10"},
+		{`reduce(["a", "b"], fn(acc, x) { acc + x }, ">")`, ">ab"},
+		{"reduce([], fn(acc, x) { acc + x }, 0)", "0"},
+
+		{"sort([3, 1, 2])", "[1, 2, 3]"},
+		{"sort([2.5, 1, -3, 2])", "[-3, 1, 2, 2.500000]"},
+		{`sort(["pear", "apple", "fig"])`, "[apple, fig, pear]"},
+		{`sort(["pear", "apple", "fig"], len)`, "[fig, pear, apple]"},
+		{"sort([3, 1, 2], fn(a, b) { a > b })", "[3, 2, 1]"},
+		{"sort([3, 1, 2], fn(a, b) { b - a })", "[3, 2, 1]"},
+		{`sort([[2, "b"], [1, "x"], [2, "a"], [1, "y"]], fn(p) { p[0] })`, "[[1, x], [1, y], [2, b], [2, a]]"},
+		{"let a = [2, 1]; sort(a); a", "[2, 1]"},
+		{"sort([])", "[]"},
+
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{"zip([1, 2], [3, 4], [5, 6])", "[[1, 3, 5], [2, 4, 6]]"},
+		{`enumerate(["a", "b"])`, "[[0, a], [1, b]]"},
+		{"flatten([1, [2, [3, [4]]], []])", "[1, 2, [3, [4]]]"},
+		{"flatten([1, [2, [3, [4]]]], 2)", "[1, 2, 3, [4]]"},
+		{"flatten([[1]], 0)", "[[1]]"},
+		{"flatten([])", "[]"},
+		{"reverse([1, 2, 3])", "[3, 2, 1]"},
+		{`unique([1, 2, 1, "1", 3, 2, true, true])`, "[1, 2, 1, 3, true]"},
+		{"unique([1, 1.0, 2.5, 2.5])", "[1, 2.500000]"},
+		{"unique([2.0, 2, 0.0, -0.0, 0.5 + 0.25, 0.75])", "[2.000000, 0.000000, 0.750000]"},
+		{"len(unique([0.0 / 0.0, 0.0 / 0.0]))", "2"},
+		{"chunk([1, 2, 3, 4, 5], 2)", "[[1, 2], [3, 4], [5]]"},
+		{"chunk([], 3)", "[]"},
+		{"any([1, 2, 3], fn(x) { x > 2 })", "true"},
+		{"any([1, 2, 3], fn(x) { x > 3 })", "false"},
+		{"any([])", "false"},
+		{"any([null, false, 0])", "true"},
+		{"all([1, 2, 3], fn(x) { x > 0 })", "true"},
+		{"all([1, null])", "false"},
+		{"all([])", "true"},
+		{"sum([1, 2, 3])", "6"},
+		{"sum([1, 2.5])", "3.500000"},
+		{"sum([])", "0"},
+		{"sum([9223372036854775807, -1, 1])", "9223372036854775807"},
+		{"sum([9223372036854775807, 1, 0.5])", "9223372036854775808.000000"},
+		{"sum([-9223372036854775807, -1])", "-9223372036854775808"},
+		{"min([3, 1.5, 2])", "1.500000"},
+		{"max([3, 1.5, 2])", "3"},
+		{`max(["b", "c", "a"])`, "c"},
+		{"min(4, 2, 8)", "2"},
+		{"max([1, 1.0])", "1"},
+
+		{"map(1, fn(x) { x })", "Error (1:4) -> argument 1 to `map` must be ARRAY, got INTEGER"},
+		{"map([1], 2)", "Error (1:4) -> argument 2 to `map` must be FUNCTION, got INTEGER"},
+		{"map([1])", "Error (1:4) -> wrong number of arguments: want=2, got=1"},
+		{"map([1], fn() { 1 })", "Error (1:4) -> wrong number of arguments: want=0, got=1"},
+		{"filter([1, 2], fn(x) {\n  x + true\n})", "Error (2:5) -> type mismatch: INTEGER + BOOLEAN"},
+		{"reduce([], fn(acc, x) { acc })", "Error (1:7) -> reduce of an empty array with no initial value"},
+		{`sort([1, "a"])`, "Error (1:5) -> cannot compare STRING and INTEGER"},
+		{"sort([1, 2], fn(a, b) { null })", "Error (1:5) -> comparator passed to `sort` must return INTEGER or BOOLEAN, got NULL"},
+		{"sort([1, 2], fn(a, b) { a + true })", "Error (1:27) -> type mismatch: INTEGER + BOOLEAN"},
+		{"zip()", "Error (1:4) -> wrong number of arguments: want at least 1, got=0"},
+		{"zip([1], 2)", "Error (1:4) -> argument 2 to `zip` must be ARRAY, got INTEGER"},
+		{"flatten([1], -1)", "Error (1:8) -> flatten depth must not be negative, got -1"},
+		{"unique([[1], [1]])", "Error (1:7) -> unusable as hash key: ARRAY"},
+		{"chunk([1], 0)", "Error (1:6) -> chunk size must be positive, got 0"},
+		{`chunk([1], "2")`, "Error (1:6) -> argument 2 to `chunk` must be INTEGER, got STRING"},
+		{"sum([9223372036854775807, 1])", "Error (1:4) -> `sum` overflows INTEGER"},
+		{"sum([-9223372036854775807, -2])", "Error (1:4) -> `sum` overflows INTEGER"},
+		{`sum([1, "2"])`, "Error (1:4) -> argument to `sum` must only hold INTEGER and FLOAT, got STRING at [1]"},
+		{"min([])", "Error (1:4) -> `min` of an empty array"},
+		{"max([true, false])", "Error (1:4) -> cannot compare BOOLEAN and BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCollectionCallbackStack(t *testing.T) {
+	input := "let check = fn(x) { assert(x < 3, \"too big\") };\nlet run = fn() { map([1, 2, 3], check) };\nrun()"
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+	if errObj.Inspect() != "Error (1:27) -> assertion failed: too big" {
+		t.Errorf("wrong error %q", errObj.Inspect())
+	}
+
+	want := []object.Frame{
+		{Function: "check", Line: 2, Col: 21},
+		{Function: "run", Line: 3, Col: 4},
+	}
+	if len(errObj.Stack) != len(want) || errObj.Stack[0] != want[0] || errObj.Stack[1] != want[1] {
+		t.Errorf("wrong stack. want=%+v, got=%+v", want, errObj.Stack)
+	}
+}
+
+func TestCollectionLimits(t *testing.T) {
+	program := parser.New(lexer.New("map([1, 2, 3, 4, 5, 6, 7, 8], str)")).ParseProgram()
+	evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), Limits{MaxSteps: 15})
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || !errors.Is(errObj.Err, ErrStepLimit) {
+		t.Errorf("callbacks of builtins not counted as steps. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestCollectionIterable(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("numbers", &countdown{from: 3})
+
+	program := parser.New(lexer.New("[map(numbers, fn(x) { x * 10 }), sum(numbers), sort(numbers)]")).ParseProgram()
+	if evaluated := Eval(program, env); evaluated.Inspect() != "[[30, 20, 10], 6, [1, 2, 3]]" {
+		t.Errorf("wrong result %s", evaluated.Inspect())
+	}
+}
+
+func TestCollectionIterableError(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("numbers", &countdown{from: -1})
+
+	program := parser.New(lexer.New("sum(numbers)")).ParseProgram()
+	errObj, ok := Eval(program, env).(*object.Error)
+	if !ok || !errors.Is(errObj.Err, errNegative) {
+		t.Errorf("expected an error caused by errNegative, got %v", errObj)
+	}
+}
+
+// countdown is an iterable host object yielding from, from-1, ... 1. It
+// fails if from is negative.
+type countdown struct{ from int64 }
+
+var errNegative = errors.New("negative start")
+
+func (c *countdown) Type() object.ObjectType { return "COUNTDOWN" }
+func (c *countdown) Inspect() string         { return "countdown" }
+
+func (c *countdown) Iterate(yield func(object.Object) bool) error {
+	if c.from < 0 {
+		return errNegative
+	}
+	for i := c.from; i > 0; i-- {
+		if !yield(&object.Integer{Value: i}) {
+			break
+		}
+	}
+	return nil
+}
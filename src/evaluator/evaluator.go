@@ -1,38 +1,57 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"math"
 
 	"github.com/salty-max/lars/src/ast"
 	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/suggest"
 	"github.com/salty-max/lars/src/token"
 )
 
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
-	NULL  = &object.Null{}
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
+	NULL  = object.NULL
 )
 
-func Eval(node ast.Node) object.Object {
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	if limiter := env.Limiter(); limiter != nil {
+		if err := limiter.Step(); err != nil {
+			return nodeLimitError(node, err)
+		}
+	}
+
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
-		return evalProgram(node)
+		return evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression)
+		return Eval(node.Expression, env)
 	case *ast.BlockStatement:
-		return evalBlockStatements(node)
+		return evalBlockStatements(node, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node)
+		return evalIfExpression(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue)
+		val := Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 
 		return &object.ReturnValue{Value: val}
+	case *ast.VarDeclStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+			fn.Name = node.Name.Value
+		}
+		env.Set(node.Name.Value, val)
 
 		// Expressions
 	case *ast.IntegerLiteral:
@@ -43,39 +62,93 @@ func Eval(node ast.Node) object.Object {
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.Null:
 		return NULL
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+
+		return allocated(node, env, &object.Array{Elements: elements})
+	case *ast.HashLiteral:
+		return allocated(node, env, evalHashLiteral(node, env))
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		return evalIndexExpression(node.Token, left, index)
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env}
+	case *ast.MemberExpression:
+		obj := Eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+
+		return evalMemberExpression(node.Property, obj)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	case *ast.CallExpression:
+		if member, ok := node.Function.(*ast.MemberExpression); ok {
+			return evalMethodCall(node, member, env)
+		}
+		function := Eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+
+		result := applyFunction(node.Token, env, function, args)
+		if _, ok := function.(*object.Builtin); ok {
+			// functions written in lars account for their own allocations
+			return allocated(node, env, result)
+		}
+		return result
 	case *ast.PrefixExpression:
-		right := Eval(node.Right)
+		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 
 		return evalPrefixExpression(node.Token, node.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(node.Left)
+		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right)
+		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 
-		return evalInfixExpression(node.Token, node.Operator, left, right)
+		return allocated(node, env, evalInfixExpression(node.Token, node.Operator, left, right))
 	}
 
 	return nil
 }
 
-func evalProgram(program *ast.Program) object.Object {
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement)
+		result = Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
 			return result.Value
-		case *object.Error:
+		case *object.Error, *object.Exit:
 			return result
 		}
 	}
@@ -83,11 +156,11 @@ func evalProgram(program *ast.Program) object.Object {
 	return result
 }
 
-func evalStatements(stmts []ast.Statement) object.Object {
+func evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
 	var res object.Object
 
 	for _, stmt := range stmts {
-		res = Eval(stmt)
+		res = Eval(stmt, env)
 
 		if returnValue, ok := res.(*object.ReturnValue); ok {
 			return returnValue.Value
@@ -97,15 +170,15 @@ func evalStatements(stmts []ast.Statement) object.Object {
 	return res
 }
 
-func evalBlockStatements(block *ast.BlockStatement) object.Object {
+func evalBlockStatements(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement)
+		result = Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.EXIT_OBJ {
 				return result
 			}
 		}
@@ -114,21 +187,207 @@ func evalBlockStatements(block *ast.BlockStatement) object.Object {
 	return result
 }
 
-func evalIfExpression(ie *ast.IfExpression) object.Object {
-	condition := Eval(ie.Condition)
+func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence)
+		return Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative)
+		return Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+
+	err := newError(node.Token, "identifier not found: %s", node.Value)
+	candidates := append(env.Names(), BuiltinNames()...)
+	candidates = append(candidates, token.Keywords()...)
+	if match, ok := suggest.Closest(node.Value, candidates); ok {
+		err.Hint = fmt.Sprintf("did you mean `%s`?", match)
+	}
+
+	return err
+}
+
+func evalExpressions(exprs []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, e := range exprs {
+		evaluated := Eval(e, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+func evalIndexExpression(token token.Token, left, index object.Object) object.Object {
+	if indexer, ok := left.(object.Indexer); ok {
+		value, err := indexer.Index(index)
+		if err != nil {
+			return newError(token, "%s: %s", left.Type(), err)
+		}
+		if value == nil {
+			return NULL
+		}
+		return value
+	}
+
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(token, left, index)
+	default:
+		return newError(token, "index operator not supported: %s", left.Type())
+	}
+}
+
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	elements := array.(*object.Array).Elements
+	idx := index.(*object.Integer).Value
+	max := int64(len(elements) - 1)
+
+	if idx < 0 || idx > max {
+		return NULL
+	}
+
+	return elements[idx]
+}
+
+func evalHashIndexExpression(token token.Token, hash, index object.Object) object.Object {
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError(token, "unusable as hash key: %s", index.Type())
+	}
+
+	value, ok := hash.(*object.Hash).Get(key)
+	if !ok {
+		return NULL
+	}
+
+	return value
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			start, _ := ast.Span(pair.Key)
+			return newError(
+				token.Token{Line: start.Line, Col: start.Col},
+				"unusable as hash key: %s",
+				key.Type(),
+			)
+		}
+
+		value := Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash
+}
+
+// applyFunction calls fn with args. env is the environment of the caller,
+// which builtins receive.
+func applyFunction(tok token.Token, env *object.Environment, fn object.Object, args []object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError(
+				tok,
+				"wrong number of arguments: want=%d, got=%d",
+				len(fn.Parameters),
+				len(args),
+			)
+		}
+
+		if limiter := fn.Env.Limiter(); limiter != nil {
+			if err := limiter.Enter(); err != nil {
+				return limitError(tok, err)
+			}
+			defer limiter.Leave()
+		}
+		trace(fn.Env, "function", fn.Name, tok, len(args))
+
+		extendedEnv := extendFunctionEnv(fn, args)
+		evaluated := Eval(fn.Body, extendedEnv)
+		if err, ok := evaluated.(*object.Error); ok {
+			err.Stack = append(err.Stack, object.Frame{Function: fn.Name, Line: tok.Line, Col: tok.Col})
+		}
+		return unwrapReturnValue(evaluated)
+	case *object.Builtin:
+		trace(env, "builtin", fn.Name, tok, len(args))
+		result := fn.Fn(env, args...)
+		if err, ok := result.(*object.Error); ok {
+			if err.Line == 0 {
+				err.Line, err.Col = tok.Line, tok.Col
+			}
+			// functions the builtin called back were called from here
+			for i := range err.Stack {
+				if err.Stack[i].Line == 0 {
+					err.Stack[i].Line, err.Stack[i].Col = tok.Line, tok.Col
+				}
+			}
+		}
+		return result
+	default:
+		return newError(tok, "not a function: %s", fn.Type())
+	}
+}
+
+// trace logs a call made at tok to the logger of env, if it has one.
+func trace(env *object.Environment, msg, name string, tok token.Token, args int) {
+	logger := env.Logger()
+	if logger == nil || !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	logger.Debug(msg, "fn", name, "args", args, "line", tok.Line, "col", tok.Col)
+}
+
+func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	env := object.NewEnclosedEnvironment(fn.Env)
+
+	for i, param := range fn.Parameters {
+		env.Set(param.Value, args[i])
+	}
+
+	return env
+}
+
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+
+	return obj
+}
+
 func evalPrefixExpression(token token.Token, operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
@@ -166,6 +425,8 @@ func evalInfixExpression(
 		)
 	case left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ:
 		return evalBooleanInfixExpression(token, operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(token, operator, left, right)
 	default:
 		return newError(token, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	}
@@ -187,8 +448,14 @@ func evalIntegerInfixExpression(
 	case "*":
 		return &object.Integer{Value: leftValue * rightValue}
 	case "/":
+		if rightValue == 0 {
+			return newError(token, "division by zero")
+		}
 		return &object.Integer{Value: leftValue / rightValue}
 	case "%":
+		if rightValue == 0 {
+			return newError(token, "division by zero")
+		}
 		return &object.Integer{Value: leftValue % rightValue}
 	case "<":
 		return nativeBoolToBooleanObject(leftValue < rightValue)
@@ -261,19 +528,30 @@ func evalBooleanInfixExpression(
 	}
 }
 
-func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
+func evalStringInfixExpression(
+	token token.Token,
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftValue + rightValue}
+	case "==":
+		return nativeBoolToBooleanObject(leftValue == rightValue)
+	case "!=":
+		return nativeBoolToBooleanObject(leftValue != rightValue)
 	default:
-		return FALSE
+		return newError(token, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+func evalBangOperatorExpression(right object.Object) object.Object {
+	return nativeBoolToBooleanObject(!isTruthy(right))
+}
+
 func evalMinusPrefixOperatorExpression(token token.Token, right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ && right.Type() != object.FLOAT_OBJ {
 		return newError(token, "unknown operator: -%s", right.Type())
@@ -289,20 +567,17 @@ func evalMinusPrefixOperatorExpression(token token.Token, right object.Object) o
 }
 
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
-	if input {
-		return TRUE
-	}
-	return FALSE
+	return object.NativeBool(input)
 }
 
+// isTruthy compares by value rather than against the TRUE, FALSE and NULL
+// singletons, since host code may create its own booleans and nulls.
 func isTruthy(obj object.Object) bool {
-	switch obj {
-	case NULL:
-		return false
-	case TRUE:
-		return true
-	case FALSE:
+	switch obj := obj.(type) {
+	case *object.Null:
 		return false
+	case *object.Boolean:
+		return obj.Value
 	default:
 		return true
 	}
@@ -312,9 +587,19 @@ func newError(token token.Token, format string, a ...interface{}) *object.Error
 	return &object.Error{Line: token.Line, Col: token.Col, Message: fmt.Sprintf(format, a...)}
 }
 
+// isError reports whether obj must unwind the evaluation: an error, or a
+// request to exit.
 func isError(obj object.Object) bool {
 	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+		return obj.Type() == object.ERROR_OBJ || obj.Type() == object.EXIT_OBJ
 	}
 	return false
 }
+
+// Apply calls fn, a function or builtin, with args. It lets host code call
+// back into lars; errors raised outside any lars code have no position.
+// Builtins are called without an environment, so they are not sandboxed;
+// use ApplyContext to call them on behalf of an environment.
+func Apply(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(token.Token{}, nil, fn, args)
+}
@@ -0,0 +1,213 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/token"
+)
+
+// Errors reported when an evaluation is stopped by one of its Limits. They
+// are wrapped in the Err field of the resulting *object.Error.
+var (
+	ErrStepLimit  = errors.New("step limit exceeded")
+	ErrTimeout    = errors.New("evaluation timed out")
+	ErrDepthLimit = errors.New("maximum call depth exceeded")
+	ErrAllocLimit = errors.New("allocation limit exceeded")
+)
+
+// DefaultMaxDepth is how deeply function calls may nest when Limits leaves
+// MaxDepth zero. Deeper recursion would overflow the Go stack, which aborts
+// the whole process rather than failing the evaluation.
+const DefaultMaxDepth = 10_000
+
+// Limits bounds the resources an evaluation may use. A zero field means no
+// limit, except for MaxDepth.
+type Limits struct {
+	// MaxSteps is the number of nodes that may be evaluated.
+	MaxSteps int64
+	// Timeout is the wall-clock time the evaluation may take. It cannot
+	// interrupt a builtin or host function that blocks.
+	Timeout time.Duration
+	// MaxDepth is how deeply function calls may nest. Zero means
+	// DefaultMaxDepth, and a negative value means no limit, for hosts
+	// that raise the Go stack limit with debug.SetMaxStack.
+	MaxDepth int
+	// MaxAlloc is the approximate number of bytes that may be allocated
+	// for strings, arrays and hashes over the whole evaluation. Memory
+	// freed along the way is not given back.
+	MaxAlloc int64
+}
+
+// valueSize is the size charged for each element of an array, and for
+// each key and value of a hash.
+const valueSize = 16
+
+// checkEvery is how many steps pass between checks of the context and the
+// deadline, which are too slow to make on every step. The first step is
+// always checked.
+const checkEvery = 256
+
+// meter is the object.Limiter that enforces Limits.
+type meter struct {
+	ctx      context.Context
+	limits   Limits
+	deadline time.Time
+
+	steps int64
+	depth int
+	alloc int64
+
+	// err is the first limit exceeded. Once set, every check fails with
+	// it, so that code which ignores an error cannot carry on.
+	err error
+}
+
+func newMeter(ctx context.Context, limits Limits) *meter {
+	m := &meter{ctx: ctx, limits: limits}
+	if limits.MaxDepth == 0 {
+		m.limits.MaxDepth = DefaultMaxDepth
+	}
+	if limits.Timeout > 0 {
+		m.deadline = time.Now().Add(limits.Timeout)
+	}
+	return m
+}
+
+func (m *meter) Step() error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.steps++
+	if m.limits.MaxSteps > 0 && m.steps > m.limits.MaxSteps {
+		return m.fail(fmt.Errorf("%w (%d steps)", ErrStepLimit, m.limits.MaxSteps))
+	}
+
+	if m.steps%checkEvery == 1 {
+		switch err := m.ctx.Err(); {
+		case errors.Is(err, context.DeadlineExceeded):
+			return m.fail(fmt.Errorf("%w: %w", ErrTimeout, err))
+		case err != nil:
+			return m.fail(fmt.Errorf("evaluation stopped: %w", err))
+		}
+		if !m.deadline.IsZero() && time.Now().After(m.deadline) {
+			return m.fail(fmt.Errorf("%w after %s", ErrTimeout, m.limits.Timeout))
+		}
+	}
+
+	return nil
+}
+
+func (m *meter) Enter() error {
+	if m.err != nil {
+		return m.err
+	}
+
+	if m.limits.MaxDepth > 0 && m.depth >= m.limits.MaxDepth {
+		return m.fail(fmt.Errorf("%w (%d calls)", ErrDepthLimit, m.limits.MaxDepth))
+	}
+	m.depth++
+	return nil
+}
+
+func (m *meter) Leave() {
+	m.depth--
+}
+
+func (m *meter) Alloc(size int64) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.alloc += size
+	if m.limits.MaxAlloc > 0 && m.alloc > m.limits.MaxAlloc {
+		return m.fail(fmt.Errorf("%w (%d bytes)", ErrAllocLimit, m.limits.MaxAlloc))
+	}
+	return nil
+}
+
+func (m *meter) fail(err error) error {
+	m.err = err
+	return err
+}
+
+// EvalContext evaluates node in env like Eval, within limits. It stops with
+// an error when a limit is exceeded or ctx is done; a ctx deadline counts
+// as a timeout. The limits cover any function defined in env that host
+// code calls back into meanwhile. If env is already being evaluated under
+// limits, as when a host function calls back into it, ctx and limits are
+// ignored: the evaluation goes on within the remaining budget.
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment, limits Limits) object.Object {
+	restore := limit(ctx, env, limits)
+	defer restore()
+
+	return Eval(node, env)
+}
+
+// ApplyContext calls fn with args like Apply, within limits, on behalf of
+// env: builtins receive env, and are subject to its grants. Like
+// EvalContext, it shares the budget of an evaluation already running in
+// env.
+func ApplyContext(ctx context.Context, env *object.Environment, limits Limits, fn object.Object, args ...object.Object) object.Object {
+	restore := limit(ctx, env, limits)
+	defer restore()
+
+	return applyFunction(token.Token{}, env, fn, args)
+}
+
+// limit puts env under limits, and returns a function that lifts them. If
+// env already has a limiter, it is kept, so that nested evaluations cannot
+// reset the steps, time and memory used so far.
+func limit(ctx context.Context, env *object.Environment, limits Limits) (restore func()) {
+	if env.Limiter() != nil {
+		return func() {}
+	}
+	env.SetLimiter(newMeter(ctx, limits))
+
+	return func() { env.SetLimiter(nil) }
+}
+
+// limitError turns err, returned by a limiter, into a lars error at tok.
+func limitError(tok token.Token, err error) *object.Error {
+	e := newError(tok, "%s", err)
+	e.Err = err
+	return e
+}
+
+// nodeLimitError is limitError at the start of node.
+func nodeLimitError(node ast.Node, err error) *object.Error {
+	start, _ := ast.Span(node)
+	return limitError(token.Token{Line: start.Line, Col: start.Col}, err)
+}
+
+// allocated reports to the limiter of env the size of obj, which node has
+// just created. It returns obj, or an error if the allocation limit is
+// exceeded.
+func allocated(node ast.Node, env *object.Environment, obj object.Object) object.Object {
+	limiter := env.Limiter()
+	if limiter == nil {
+		return obj
+	}
+
+	var size int64
+	switch obj := obj.(type) {
+	case *object.String:
+		size = int64(len(obj.Value))
+	case *object.Array:
+		size = int64(len(obj.Elements)) * valueSize
+	case *object.Hash:
+		size = int64(obj.Len()) * 2 * valueSize
+	default:
+		return obj
+	}
+
+	if err := limiter.Alloc(size); err != nil {
+		return nodeLimitError(node, err)
+	}
+	return obj
+}
@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+)
+
+const fib = "let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };"
+
+func testEvalLimited(ctx context.Context, input string, limits Limits) (object.Object, *object.Environment) {
+	env := object.NewEnvironment()
+	program := parser.New(lexer.New(input)).ParseProgram()
+	return EvalContext(ctx, program, env, limits), env
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limits   Limits
+		expected error
+		message  string
+	}{
+		{
+			"steps",
+			fib + "fib(15)",
+			Limits{MaxSteps: 1000},
+			ErrStepLimit,
+			"step limit exceeded (1000 steps)",
+		},
+		{
+			"depth",
+			"let f = fn() { f() };\nf()",
+			Limits{MaxDepth: 100},
+			ErrDepthLimit,
+			"Error (1:17) -> maximum call depth exceeded (100 calls)",
+		},
+		{
+			"alloc",
+			`let grow = fn(s, n) { if (n == 0) { s } else { grow(s + s, n - 1) } }; grow("ab", 30)`,
+			Limits{MaxAlloc: 1 << 20},
+			ErrAllocLimit,
+			"allocation limit exceeded (1048576 bytes)",
+		},
+		{
+			"array alloc",
+			"let a = [1, 2, 3, 4, 5, 6, 7, 8]; [a, a]",
+			Limits{MaxAlloc: 150},
+			ErrAllocLimit,
+			"Error (1:35) -> allocation limit exceeded (150 bytes)",
+		},
+		{
+			"timeout",
+			fib + "fib(40)",
+			Limits{Timeout: 10 * time.Millisecond},
+			ErrTimeout,
+			"evaluation timed out after 10ms",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated, env := testEvalLimited(context.Background(), tt.input, tt.limits)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned. got=%T (%+v)", tt.name, evaluated, evaluated)
+			continue
+		}
+		if !errors.Is(errObj.Err, tt.expected) {
+			t.Errorf("%s: wrong error kind. want=%v, got=%v", tt.name, tt.expected, errObj.Err)
+		}
+		if errObj.Message != tt.message && errObj.Inspect() != tt.message {
+			t.Errorf("%s: wrong error. want=%q, got=%q", tt.name, tt.message, errObj.Inspect())
+		}
+		if env.Limiter() != nil {
+			t.Errorf("%s: limiter left in place after evaluation", tt.name)
+		}
+	}
+}
+
+func TestWithinLimits(t *testing.T) {
+	limits := Limits{MaxSteps: 100_000, Timeout: time.Minute, MaxDepth: 50, MaxAlloc: 1 << 20}
+	evaluated, _ := testEvalLimited(context.Background(), fib+`fib(15); "a" + "b"`, limits)
+	if evaluated.Inspect() != "ab" {
+		t.Errorf("wrong result. want=%q, got=%q", "ab", evaluated.Inspect())
+	}
+}
+
+func TestEvalContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	evaluated, _ := testEvalLimited(ctx, "1 + 2", Limits{})
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+	if !errors.Is(errObj.Err, context.Canceled) {
+		t.Errorf("wrong error kind. want=%v, got=%v", context.Canceled, errObj.Err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	evaluated, _ = testEvalLimited(ctx, fib+"fib(40)", Limits{})
+	errObj, ok = evaluated.(*object.Error)
+	if !ok || !errors.Is(errObj.Err, ErrTimeout) || !errors.Is(errObj.Err, context.DeadlineExceeded) {
+		t.Errorf("context deadline not reported as a timeout. got=%+v", evaluated)
+	}
+}
+
+func TestApplyContext(t *testing.T) {
+	env := object.NewEnvironment()
+	Eval(parser.New(lexer.New(fib)).ParseProgram(), env)
+	fn, _ := env.Get("fib")
+
+	result := ApplyContext(context.Background(), env, Limits{MaxSteps: 100}, fn, &object.Integer{Value: 20})
+	errObj, ok := result.(*object.Error)
+	if !ok || !errors.Is(errObj.Err, ErrStepLimit) {
+		t.Errorf("step limit not enforced in callback. got=%+v", result)
+	}
+
+	testIntegerObject(t, ApplyContext(context.Background(), env, Limits{}, fn, &object.Integer{Value: 10}), 55)
+}
@@ -0,0 +1,172 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/suggest"
+	"github.com/salty-max/lars/src/token"
+)
+
+// evalMemberExpression reads the attribute name of obj. Hashes expose their
+// string keys as attributes, and a missing one is NULL, as with indexing.
+func evalMemberExpression(name token.Token, obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case object.HostObject:
+		value, err := obj.GetAttr(name.Literal)
+		return hostResult(name, obj, name.Literal, value, err)
+	case *object.Hash:
+		if value, ok := obj.Get(&object.String{Value: name.Literal}); ok {
+			return value
+		}
+		return NULL
+	default:
+		return newError(name, "attribute access not supported: %s", obj.Type())
+	}
+}
+
+// evalMethodCall calls obj.name(args). Methods of host objects are called
+// through CallMethod; for other values, the attribute is read and called
+// like any function.
+func evalMethodCall(node *ast.CallExpression, member *ast.MemberExpression, env *object.Environment) object.Object {
+	obj := Eval(member.Object, env)
+	if isError(obj) {
+		return obj
+	}
+
+	host, ok := obj.(object.HostObject)
+	if !ok {
+		function := evalMemberExpression(member.Property, obj)
+		if isError(function) {
+			return function
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+
+		return applyFunction(node.Token, env, function, args)
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	value, err := host.CallMethod(member.Property.Literal, args...)
+	if errors.Is(err, object.ErrNoAttr) {
+		return hostResult(member.Property, host, member.Property.Literal, value, err)
+	}
+	return hostResult(node.Token, host, member.Property.Literal, value, err)
+}
+
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.MemberExpression:
+		obj := Eval(target.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		return assignAttr(target.Property, obj, value)
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		return assignIndex(target.Token, left, index, value)
+	default:
+		return newError(node.Token, "cannot assign to %s", node.Target)
+	}
+}
+
+func assignAttr(name token.Token, obj, value object.Object) object.Object {
+	switch obj := obj.(type) {
+	case object.HostObject:
+		if err := obj.SetAttr(name.Literal, value); err != nil {
+			return hostResult(name, obj, name.Literal, nil, err)
+		}
+	case *object.Hash:
+		obj.Set(&object.String{Value: name.Literal}, value)
+	default:
+		return newError(name, "attribute assignment not supported: %s", obj.Type())
+	}
+
+	return value
+}
+
+func assignIndex(tok token.Token, left, index, value object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError(tok, "array index must be INTEGER, got %s", index.Type())
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError(tok, "index out of range: %d with length %d", idx.Value, len(left.Elements))
+		}
+		left.Elements[idx.Value] = value
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError(tok, "unusable as hash key: %s", index.Type())
+		}
+		left.Set(key, value)
+	case object.Indexer:
+		if err := left.SetIndex(index, value); err != nil {
+			e := newError(tok, "%s: %s", left.Type(), err)
+			e.Err = err
+			return e
+		}
+	default:
+		return newError(tok, "index assignment not supported: %s", left.Type())
+	}
+
+	return value
+}
+
+// hostResult turns what a host object method returned into a lars value: a
+// nil value becomes NULL, and an error becomes a lars error at tok.
+func hostResult(tok token.Token, obj object.Object, name string, value object.Object, err error) object.Object {
+	if err != nil {
+		return hostError(tok, obj, name, err)
+	}
+	if value == nil {
+		return NULL
+	}
+	return value
+}
+
+// hostError reports err, returned by the member name of a host object. The
+// error keeps err as its cause.
+func hostError(tok token.Token, obj object.Object, name string, err error) *object.Error {
+	if !errors.Is(err, object.ErrNoAttr) {
+		e := newError(tok, "%s.%s: %s", obj.Type(), name, err)
+		e.Err = err
+		return e
+	}
+
+	e := newError(tok, "%s has no attribute `%s`", obj.Type(), name)
+	e.Err = err
+	if lister, ok := obj.(object.AttrLister); ok {
+		if match, ok := suggest.Closest(name, lister.AttrNames()); ok {
+			e.Hint = fmt.Sprintf("did you mean `%s`?", match)
+		}
+	}
+	return e
+}
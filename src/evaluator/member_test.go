@@ -0,0 +1,222 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+)
+
+// store is a host object standing in for a key/value database handle.
+type store struct {
+	name   string
+	data   map[string]object.Object
+	closed bool
+}
+
+var (
+	errClosed  = errors.New("already closed")
+	errKeyType = errors.New("key must be STRING")
+)
+
+func newStore() *store {
+	return &store{name: "main", data: map[string]object.Object{}}
+}
+
+func (s *store) Type() object.ObjectType { return "STORE" }
+func (s *store) Inspect() string         { return "<store " + s.name + ">" }
+
+func (s *store) GetAttr(name string) (object.Object, error) {
+	switch name {
+	case "name":
+		return &object.String{Value: s.name}, nil
+	case "closed":
+		return object.NativeBool(s.closed), nil
+	case "missing":
+		return nil, nil
+	}
+	return nil, object.ErrNoAttr
+}
+
+func (s *store) SetAttr(name string, value object.Object) error {
+	if name != "name" {
+		return fmt.Errorf("%s is read-only: %w", name, object.ErrNoAttr)
+	}
+	str, ok := value.(*object.String)
+	if !ok {
+		return fmt.Errorf("name must be STRING, got %s", value.Type())
+	}
+	s.name = str.Value
+	return nil
+}
+
+func (s *store) CallMethod(name string, args ...object.Object) (object.Object, error) {
+	switch name {
+	case "size":
+		return &object.Integer{Value: int64(len(s.data))}, nil
+	case "close":
+		if s.closed {
+			return nil, errClosed
+		}
+		s.closed = true
+		return nil, nil
+	}
+	return nil, object.ErrNoAttr
+}
+
+func (s *store) AttrNames() []string { return []string{"name", "closed", "size", "close"} }
+
+func (s *store) Index(key object.Object) (object.Object, error) {
+	if value, ok := s.data[key.Inspect()]; ok {
+		return value, nil
+	}
+	return nil, nil
+}
+
+func (s *store) SetIndex(key, value object.Object) error {
+	if _, ok := key.(*object.String); !ok {
+		return fmt.Errorf("%w, got %s", errKeyType, key.Type())
+	}
+	s.data[key.Inspect()] = value
+	return nil
+}
+
+func testEvalWith(input string, db *store) object.Object {
+	env := object.NewEnvironment()
+	env.Set("db", db)
+	program := parser.New(lexer.New(input)).ParseProgram()
+	return Eval(program, env)
+}
+
+func TestHostObjects(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"db", "<store main>"},
+		{"db.name", "main"},
+		{"db.missing", "null"},
+		{`db.name = "users"; db.name`, "users"},
+		{`db.name = "users"`, "users"},
+		{"db.size()", "0"},
+		{`db["a"] = 1; db["b"] = 2; db.size()`, "2"},
+		{`db["a"] = 1; db["a"] + db["b"]`, "Error (1:22) -> type mismatch: INTEGER + NULL"},
+		{"db.close(); db.closed", "true"},
+		{"let close = fn(d) { d.close() }; close(db)", "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWith(tt.input, newStore())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHostObjectErrors(t *testing.T) {
+	tests := []struct {
+		input        string
+		expected     string
+		expectedHint string
+	}{
+		{"db.nmae", "Error (1:4) -> STORE has no attribute `nmae`", "did you mean `name`?"},
+		{"db.sise()", "Error (1:4) -> STORE has no attribute `sise`", "did you mean `size`?"},
+		{"db.closed = true", "Error (1:4) -> STORE has no attribute `closed`", "did you mean `close`?"},
+		{"db.name = 1", "Error (1:4) -> STORE.name: name must be STRING, got INTEGER", ""},
+		{"db.close(); db.close()", "Error (1:21) -> STORE.close: already closed", ""},
+		{"db[1] = 2", "Error (1:3) -> STORE: key must be STRING, got INTEGER", ""},
+		{"db.name(nope)", "Error (1:9) -> identifier not found: nope", ""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWith(tt.input, newStore())
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: no error object returned. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Inspect() != tt.expected+hintSuffix(tt.expectedHint) {
+			t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
+
+func TestHostObjectErrorsUnwrap(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"db.nmae", object.ErrNoAttr},
+		{"db.closed = true", object.ErrNoAttr},
+		{"db.close(); db.close()", errClosed},
+		{"db[1] = 2", errKeyType},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWith(tt.input, newStore())
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: no error object returned. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if !errors.Is(errObj.Err, tt.expected) {
+			t.Errorf("input %q: wrong cause. want=%v, got=%v", tt.input, tt.expected, errObj.Err)
+		}
+	}
+}
+
+func TestMembersAndAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let user = {"name": "ada"}; user.name`, "ada"},
+		{`let user = {"name": "ada"}; user.age`, "null"},
+		{`let user = {}; user.name = "ada"; user["name"]`, "ada"},
+		{`let m = {"double": fn(x) { x * 2 }}; m.double(4)`, "8"},
+		{`let h = {}; h[1] = h[2] = 3; [h[1], h[2]]`, "[3, 3]"},
+		{"let a = [1, 2, 3]; a[1] = 5; a", "[1, 5, 3]"},
+		{"let a = [1]; let b = a; b[0] = 2; a", "[2]"},
+		{`let o = {"inner": {}}; o.inner.x = 1; o`, "{inner: {x: 1}}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMemberErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5.x", "Error (1:5) -> attribute access not supported: FLOAT"},
+		{"let s = \"a\"; s.len()", "Error (1:16) -> attribute access not supported: STRING"},
+		{"let s = \"a\"; s.x = 1", "Error (1:16) -> attribute assignment not supported: STRING"},
+		{"let a = [1]; a[1] = 2", "Error (1:15) -> index out of range: 1 with length 1"},
+		{`let a = [1]; a["0"] = 2`, "Error (1:15) -> array index must be INTEGER, got STRING"},
+		{"let h = {}; h[[]] = 2", "Error (1:14) -> unusable as hash key: ARRAY"},
+		{`let s = "a"; s[0] = 2`, "Error (1:15) -> index assignment not supported: STRING"},
+		{"let h = {}; h.f(1)", "Error (1:16) -> not a function: NULL"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func hintSuffix(hint string) string {
+	if hint == "" {
+		return ""
+	}
+	return "\n\thelp: " + hint
+}
@@ -0,0 +1,412 @@
+// Package format implements canonical formatting of lars source code.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/parser"
+	"github.com/salty-max/lars/src/token"
+)
+
+// indentation is the string used for one level of indentation.
+const indentation = "  "
+
+// primary is the precedence of operands that never need parentheses.
+const primary = parser.INDEX + 1
+
+// SyntaxError is returned when the source to format does not parse.
+type SyntaxError struct {
+	Errors []parser.ParserError
+}
+
+func (e *SyntaxError) Error() string {
+	err := e.Errors[0]
+	msg := fmt.Sprintf("%d:%d: %s", err.Line, err.Col, err.Msg)
+	if len(e.Errors) > 1 {
+		msg += fmt.Sprintf(" (and %d more errors)", len(e.Errors)-1)
+	}
+	return msg
+}
+
+// Source formats src in canonical style: one statement per line, two space
+// indentation, and only the parentheses the operator precedence requires.
+// Comments and a leading shebang line are kept, and runs of blank lines
+// between statements collapse to a single one. Arrays, hashes and argument
+// lists with comments between their items are printed one item per line,
+// so that the comments stay in place; other comments inside a statement
+// go before it.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &SyntaxError{Errors: p.Errors()}
+	}
+
+	comments := l.Comments()
+	if shebang := l.Shebang(); shebang != "" {
+		// printed like a comment on the first line
+		line := token.Token{Type: token.COMMENT, Literal: strings.TrimRight(shebang, " \t\r"), Line: 1, Col: 1}
+		comments = append([]token.Token{line}, comments...)
+	}
+
+	pr := &printer{comments: comments}
+	pr.statements(program.Statements, 0)
+	pr.flushComments(0)
+
+	return pr.buf.Bytes(), nil
+}
+
+// Node writes the canonical form of node to w. Since nodes do not carry
+// comments, none are written.
+func Node(w io.Writer, node ast.Node) error {
+	pr := &printer{}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.statements(node.Statements, 0)
+	case ast.Statement:
+		pr.statement(node)
+	case ast.Expression:
+		pr.expr(node, parser.LOWEST)
+	default:
+		return fmt.Errorf("format: unsupported node type %T", node)
+	}
+
+	_, err := w.Write(pr.buf.Bytes())
+	return err
+}
+
+type printer struct {
+	buf      bytes.Buffer
+	indent   int
+	comments []token.Token // comments not printed yet, in source order
+
+	lastLine int  // last source line printed
+	fresh    bool // nothing printed yet in the current statement list
+}
+
+func (p *printer) write(s string) {
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+}
+
+func (p *printer) writeIndent() {
+	p.write(strings.Repeat(indentation, p.indent))
+}
+
+// separate writes a blank line if line is more than one line below the last
+// printed line, so that paragraphs of code stay apart.
+func (p *printer) separate(line int) {
+	if !p.fresh && line > p.lastLine+1 {
+		p.newline()
+	}
+}
+
+// flushComments prints, each on its own line, the pending comments that
+// start before line. A line of 0 flushes every pending comment.
+func (p *printer) flushComments(line int) {
+	for len(p.comments) > 0 && (line == 0 || p.comments[0].Line < line) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		p.separate(c.Line)
+		p.writeIndent()
+		p.write(c.Literal)
+		p.newline()
+
+		p.lastLine = c.Line
+		p.fresh = false
+	}
+}
+
+// trailingComment prints a pending comment that sits on line after code,
+// unless the code that follows starts on line too, next being the line it
+// starts on: the comment then belongs after that code.
+func (p *printer) trailingComment(line, next int) {
+	if line != next && len(p.comments) > 0 && p.comments[0].Line == line {
+		p.write(" " + p.comments[0].Literal)
+		p.comments = p.comments[1:]
+	}
+}
+
+// statements prints a statement list. If end is not 0, it is the line of
+// the closing brace, and comments before it are printed inside the list.
+func (p *printer) statements(list []ast.Statement, end int) {
+	p.fresh = true
+
+	for i, stmt := range list {
+		start, last := startLine(stmt), endLine(stmt)
+
+		p.flushComments(start)
+		p.separate(start)
+
+		mark := p.buf.Len()
+		p.writeIndent()
+		p.statement(stmt)
+		if len(p.comments) > 0 && p.comments[0].Line < last {
+			// comments the statement had no place for go before it
+			text := string(p.buf.Bytes()[mark:])
+			p.buf.Truncate(mark)
+			p.fresh = true
+			p.flushComments(last)
+			p.write(text)
+		}
+		next := end
+		if i < len(list)-1 {
+			next = startLine(list[i+1])
+		}
+		p.trailingComment(last, next)
+		p.newline()
+
+		p.lastLine = last
+		p.fresh = false
+	}
+
+	if end != 0 {
+		p.flushComments(end)
+	}
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.VarDeclStatement:
+		p.write(stmt.Token.Literal + " " + stmt.Name.Value + " = ")
+		p.expr(stmt.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.write("return")
+		if stmt.ReturnValue != nil {
+			p.write(" ")
+			p.expr(stmt.ReturnValue, parser.LOWEST)
+		}
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expr(stmt.Expression, parser.LOWEST)
+		if _, ok := stmt.Expression.(*ast.IfExpression); !ok {
+			p.write(";")
+		}
+	case *ast.BlockStatement:
+		p.block(stmt)
+	}
+}
+
+func (p *printer) block(block *ast.BlockStatement) {
+	hasComments := len(p.comments) > 0 && p.comments[0].Line < block.Rbrace.Line
+	if len(block.Statements) == 0 && !hasComments {
+		p.write("{}")
+		return
+	}
+
+	p.write("{")
+	p.newline()
+
+	lastLine, fresh := p.lastLine, p.fresh
+	p.lastLine = block.Token.Line
+	p.indent++
+	p.statements(block.Statements, block.Rbrace.Line)
+	p.indent--
+	p.lastLine, p.fresh = lastLine, fresh
+
+	p.writeIndent()
+	p.write("}")
+}
+
+// expr prints e, wrapped in parentheses if it binds more loosely than prec.
+func (p *printer) expr(e ast.Expression, prec int) {
+	if precedence(e) < prec {
+		p.write("(")
+		defer p.write(")")
+	}
+
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		if right, ok := e.Right.(*ast.PrefixExpression); ok && right.Operator == e.Operator {
+			// --x would lex as a decrement
+			p.write("(")
+			p.expr(e.Right, parser.LOWEST)
+			p.write(")")
+		} else {
+			p.expr(e.Right, parser.PREFIX)
+		}
+	case *ast.InfixExpression:
+		prec := parser.Precedence(e.Token.Type)
+		p.expr(e.Left, prec)
+		p.write(" " + e.Operator + " ")
+		p.expr(e.Right, prec+1)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expr(e.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.write(" else ")
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		params := make([]string, 0, len(e.Parameters))
+		for _, param := range e.Parameters {
+			params = append(params, param.Value)
+		}
+		p.write(e.Token.Literal + "(" + strings.Join(params, ", ") + ") ")
+		p.block(e.Body)
+	case *ast.CallExpression:
+		p.expr(e.Function, parser.CALL)
+		p.list("(", ")", e.Rparen.Line, p.expressionItems(e.Arguments))
+	case *ast.ArrayLiteral:
+		p.list("[", "]", e.Rbracket.Line, p.expressionItems(e.Elements))
+	case *ast.HashLiteral:
+		items := make([]item, len(e.Pairs))
+		for i, pair := range e.Pairs {
+			items[i] = item{first: pair.Key, last: pair.Value, print: func() {
+				p.expr(pair.Key, parser.LOWEST)
+				p.write(": ")
+				p.expr(pair.Value, parser.LOWEST)
+			}}
+		}
+		p.list("{", "}", e.Rbrace.Line, items)
+	case *ast.IndexExpression:
+		// calls and index expressions chain freely
+		p.expr(e.Left, parser.CALL)
+		p.write("[")
+		p.expr(e.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.MemberExpression:
+		p.expr(e.Object, parser.CALL)
+		p.write("." + e.Property.Literal)
+	case *ast.AssignExpression:
+		p.expr(e.Target, parser.ASSIGN+1)
+		p.write(" = ")
+		p.expr(e.Value, parser.ASSIGN)
+	case *ast.StringLiteral:
+		p.write(`"` + e.Token.Literal + `"`)
+	case *ast.Identifier:
+		p.write(e.Value)
+	default:
+		p.write(e.String())
+	}
+}
+
+// item is an element of a bracketed list, spanning the source from first
+// to last.
+type item struct {
+	first, last ast.Node
+	print       func()
+}
+
+// expressionItems makes the items of a list of expressions.
+func (p *printer) expressionItems(list []ast.Expression) []item {
+	items := make([]item, len(list))
+	for i, e := range list {
+		items[i] = item{first: e, last: e, print: func() { p.expr(e, parser.LOWEST) }}
+	}
+	return items
+}
+
+// list prints items between open and close, the line of which is end. The
+// items go on one line, unless comments fall between them: then each item
+// goes on a line of its own, with the comments around it where they were.
+func (p *printer) list(open, close string, end int, items []item) {
+	p.write(open)
+	if !p.commentsBetween(items, end) {
+		for i, it := range items {
+			if i > 0 {
+				p.write(", ")
+			}
+			it.print()
+		}
+		p.write(close)
+		return
+	}
+
+	p.newline()
+	lastLine, fresh := p.lastLine, p.fresh
+	p.fresh = true
+	p.indent++
+	for i, it := range items {
+		start, last := startLine(it.first), endLine(it.last)
+
+		p.flushComments(start)
+		p.separate(start)
+
+		p.writeIndent()
+		it.print()
+		next := end
+		if i < len(items)-1 {
+			p.write(",")
+			next = startLine(items[i+1].first)
+		}
+		p.trailingComment(last, next)
+		p.newline()
+
+		p.lastLine = last
+		p.fresh = false
+	}
+	p.flushComments(end)
+	p.indent--
+	p.lastLine, p.fresh = lastLine, fresh
+
+	p.writeIndent()
+	p.write(close)
+}
+
+// commentsBetween reports whether pending comments before the line end
+// fall between items rather than inside one of them, where the item
+// prints them.
+func (p *printer) commentsBetween(items []item, end int) bool {
+	for _, c := range p.comments {
+		if c.Line >= end {
+			return false
+		}
+		inside := false
+		for _, it := range items {
+			if startLine(it.first) <= c.Line && c.Line < endLine(it.last) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return true
+		}
+	}
+	return false
+}
+
+// precedence returns how tightly e binds, in parser precedence levels.
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.MemberExpression:
+		return parser.INDEX
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	default:
+		return primary
+	}
+}
+
+// startLine returns the first source line spanned by node.
+func startLine(node ast.Node) int {
+	start, _ := ast.Span(node)
+	return start.Line
+}
+
+// endLine returns the last source line spanned by node.
+func endLine(node ast.Node) int {
+	_, end := ast.Span(node)
+	return end.Line
+}
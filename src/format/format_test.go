@@ -0,0 +1,184 @@
+package format
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x=5", []string{"let x = 5;"}},
+		{"const PI=3.14;", []string{"const PI = 3.14;"}},
+		{"((a + b) * c)", []string{"(a + b) * c;"}},
+		{"(a * b) + c", []string{"a * b + c;"}},
+		{"a - (b - c)", []string{"a - (b - c);"}},
+		{"(a - b) - c", []string{"a - b - c;"}},
+		{"-(a + b)", []string{"-(a + b);"}},
+		{"-(-a)", []string{"-(-a);"}},
+		{"!(a == b)", []string{"!(a == b);"}},
+		{"(a < b) == (c > d)", []string{"a < b == c > d;"}},
+		{"(fn(x) { x })(1)", []string{"fn(x) {", "  x;", "}(1);"}},
+		{"add(1, (2 * 3))", []string{"add(1, 2 * 3);"}},
+		{"return(x)", []string{"return x;"}},
+		{`let s = "a\tb"`, []string{`let s = "a\tb";`}},
+		{"[1,(2+3),[a]][0]", []string{"[1, 2 + 3, [a]][0];"}},
+		{"(a + b)[i]", []string{"(a + b)[i];"}},
+		{`let h = {"a":(1+2),b:{},}`, []string{`let h = {"a": 1 + 2, b: {}};`}},
+		{"f(x)[0]", []string{"f(x)[0];"}},
+		{"(a.b).c(1)", []string{"a.b.c(1);"}},
+		{"(a + b).c", []string{"(a + b).c;"}},
+		{"a.x=(b[0]=1)", []string{"a.x = b[0] = 1;"}},
+		{"(a.x = 1) + 2", []string{"(a.x = 1) + 2;"}},
+		{"if(a){b}", []string{"if (a) {", "  b;", "}"}},
+		{"if (a) {} else {c}", []string{"if (a) {} else {", "  c;", "}"}},
+		{
+			"let f = fn(a,b){ return a+b }",
+			[]string{"let f = fn(a, b) {", "  return a + b;", "};"},
+		},
+		{
+			"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;",
+			[]string{"let a = 1;", "", "let b = 2;", "let c = 3;"},
+		},
+		{
+			"// header\nlet a = 1; // trailing\n\n// about b\nlet b = 2;\n// footer",
+			[]string{"// header", "let a = 1; // trailing", "", "// about b", "let b = 2;", "// footer"},
+		},
+		{
+			"#!/usr/bin/env lars\n\nlet a=1",
+			[]string{"#!/usr/bin/env lars", "", "let a = 1;"},
+		},
+		{
+			"#!/usr/bin/env lars\n// about a\nlet a=1",
+			[]string{"#!/usr/bin/env lars", "// about a", "let a = 1;"},
+		},
+		{
+			"fn() {\n  // only a comment\n}",
+			[]string{"fn() {", "  // only a comment", "};"},
+		},
+		{
+			"let add = fn(a,b){a+b}; // trailing",
+			[]string{"let add = fn(a, b) {", "  a + b;", "}; // trailing"},
+		},
+		{
+			"let a = 1; let b = 2; // about b",
+			[]string{"let a = 1;", "let b = 2; // about b"},
+		},
+		{
+			"let xs = [1,\n 2, 3, // three\n 4]",
+			[]string{"let xs = [", "  1,", "  2,", "  3, // three", "  4", "];"},
+		},
+		{
+			"fn() {\n\n  a\n  // before the brace\n}",
+			[]string{"fn() {", "  a;", "  // before the brace", "};"},
+		},
+	}
+
+	for _, tt := range tests {
+		out, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Source(%q) failed: %s", tt.input, err)
+			continue
+		}
+
+		expected := strings.Join(tt.expected, "\n") + "\n"
+		if string(out) != expected {
+			t.Errorf("Source(%q) wrong.\nwant=%q\ngot =%q", tt.input, expected, out)
+			continue
+		}
+
+		again, err := Source(out)
+		if err != nil || !bytes.Equal(again, out) {
+			t.Errorf("formatting %q is not idempotent.\nfirst =%q\nsecond=%q", tt.input, out, again)
+		}
+	}
+}
+
+func TestSourceGolden(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "comments.lars"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(filepath.Join("testdata", "comments.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Source(src)
+	if err != nil {
+		t.Fatalf("Source failed: %s", err)
+	}
+	if !bytes.Equal(out, golden) {
+		t.Errorf("output differs from comments.golden.\nwant=%s\ngot =%s", golden, out)
+	}
+	if again, err := Source(out); err != nil || !bytes.Equal(again, out) {
+		t.Errorf("formatting comments.lars is not idempotent.\nfirst =%s\nsecond=%s", out, again)
+	}
+}
+
+func TestSourcePreservesMeaning(t *testing.T) {
+	inputs := []string{
+		"1 + 2 * 3 - 4 / 5 % 6",
+		"(1 + 2) * (3 - 4) / -(5 % 6)",
+		"a == b != c < d > e <= f >= g",
+		"!-a * b(c, d + e)(f)",
+		"-a[0] * [1, 2][b + 1]",
+		"let f = fn(x) { if (x > 1) { return x * f(x - 1) } else { 1 } }",
+	}
+
+	for _, input := range inputs {
+		out, err := Source([]byte(input))
+		if err != nil {
+			t.Fatalf("Source(%q) failed: %s", input, err)
+		}
+
+		if got, want := parse(t, string(out)), parse(t, input); got != want {
+			t.Errorf("formatting changed the meaning of %q.\nwant=%s\ngot =%s", input, want, got)
+		}
+	}
+}
+
+func TestSourceSyntaxError(t *testing.T) {
+	_, err := Source([]byte("let = 5;"))
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	expected := "1:5: expected next token to be IDENT, got = instead"
+	if err.Error() != expected {
+		t.Errorf("wrong error. want=%q, got=%q", expected, err.Error())
+	}
+}
+
+func TestNode(t *testing.T) {
+	p := parser.New(lexer.New("((a + b) * c)"))
+	program := p.ParseProgram()
+
+	var buf bytes.Buffer
+	if err := Node(&buf, program.Statements[0]); err != nil {
+		t.Fatalf("Node failed: %s", err)
+	}
+
+	if buf.String() != "(a + b) * c;" {
+		t.Errorf("wrong output. got=%q", buf.String())
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %+v", input, p.Errors())
+	}
+	return program.String()
+}
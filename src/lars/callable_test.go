@@ -0,0 +1,288 @@
+package lars_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salty-max/lars/src/lars"
+	"github.com/salty-max/lars/src/object"
+)
+
+func TestCallable(t *testing.T) {
+	interp := lars.New()
+	handlers := map[string]func(context.Context, ...any) (any, error){}
+	err := interp.RegisterFunc("on", func(event string, handler object.Object) {
+		handlers[event] = interp.Callable(handler)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.RegisterFunc("upper", strings.ToUpper); err != nil {
+		t.Fatal(err)
+	}
+
+	src := `
+let adder = fn(base) { fn(x) { base + x } };
+on("add", adder(10));
+on("pair", fn(a, b) { {"a": a, "b": b} });
+on("none", fn() { });
+on("upper", upper);`
+	if _, err := interp.Eval(context.Background(), src); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		event    string
+		args     []any
+		expected any
+	}{
+		{"add", []any{32}, int64(42)},
+		{"pair", []any{"x", []int{1}}, map[string]any{"a": "x", "b": []any{int64(1)}}},
+		{"none", nil, nil},
+		{"upper", []any{"lars"}, "LARS"},
+	}
+
+	for _, tt := range tests {
+		got, err := handlers[tt.event](context.Background(), tt.args...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.event, err)
+			continue
+		}
+		if !equalValues(got, tt.expected) {
+			t.Errorf("%s: want=%#v, got=%#v", tt.event, tt.expected, got)
+		}
+	}
+
+	if _, err := handlers["add"](context.Background(), make(chan int)); err == nil {
+		t.Errorf("expected an error converting a channel argument")
+	}
+	if _, err := interp.Callable(&object.Integer{Value: 1})(context.Background()); err == nil {
+		t.Errorf("expected an error calling an integer")
+	}
+}
+
+func TestCallableFromGoroutines(t *testing.T) {
+	interp := lars.New()
+	if _, err := interp.Eval(context.Background(), `let state = {"n": 0}; let incr = fn(by) { state.n = state.n + by }`); err != nil {
+		t.Fatal(err)
+	}
+	incr, _ := interp.Get("incr")
+	call := interp.Callable(incr)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if _, err := call(context.Background(), 1); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := interp.Eval(context.Background(), "incr(1)"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, _ := interp.Eval(context.Background(), "state.n"); n.Inspect() != "2000" {
+		t.Errorf("lost updates: want=2000, got=%s", n.Inspect())
+	}
+}
+
+func TestCallableFromHostFunction(t *testing.T) {
+	interp := lars.New()
+	err := interp.RegisterFunc("sort_by", func(ctx context.Context, items []int, less object.Object) ([]int, error) {
+		cmp := interp.Callable(less)
+		var failed error
+		slices.SortStableFunc(items, func(a, b int) int {
+			lt, err := cmp(ctx, a, b)
+			if err != nil {
+				failed = err
+				return 0
+			}
+			if lt == true {
+				return -1
+			}
+			return 1
+		})
+		return items, failed
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := interp.Eval(context.Background(), `sort_by([3, 1, 2], fn(a, b) { a > b })`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inspect() != "[3, 2, 1]" {
+		t.Errorf("wrong order %s", result.Inspect())
+	}
+
+	_, err = interp.Eval(context.Background(), `sort_by([3, 1, 2], fn(a, b) { a > true })`)
+	if err == nil || err.Error() != "1:8: sort_by: 1:33: type mismatch: INTEGER > BOOLEAN" {
+		t.Errorf("wrong error from the comparator: %v", err)
+	}
+}
+
+func TestCallableFromHostGoroutines(t *testing.T) {
+	interp := lars.New(lars.WithLimits(lars.Limits{MaxSteps: 1_000_000}))
+	err := interp.RegisterFunc("fan_out", func(ctx context.Context, n int, fn object.Object) error {
+		call := interp.Callable(fn)
+		errs := make(chan error, n)
+		for range n {
+			go func() {
+				_, err := call(ctx)
+				errs <- err
+			}()
+		}
+		var all []error
+		for range n {
+			all = append(all, <-errs)
+		}
+		return errors.Join(all...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := interp.Eval(context.Background(), `let c = {"n": 0}; fan_out(20, fn() { c.n = c.n + 1 }); c.n`)
+			if err != nil || n.Inspect() != "20" {
+				t.Errorf("want=20, got=%v, %v", n, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// a callback made with the context of a finished evaluation takes its
+	// turn with the others
+	var saved context.Context
+	if err := interp.RegisterFunc("save", func(ctx context.Context) { saved = ctx }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interp.Eval(context.Background(), `let total = {"n": 0}; let bump = fn() { total.n = total.n + 1 }; save()`); err != nil {
+		t.Fatal(err)
+	}
+	bump, _ := interp.Get("bump")
+	call := interp.Callable(bump)
+	for range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := call(saved); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := interp.Eval(context.Background(), "bump()"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, _ := interp.Eval(context.Background(), "total.n"); n.Inspect() != "40" {
+		t.Errorf("lost updates: want=40, got=%s", n.Inspect())
+	}
+}
+
+func TestCallbacksShareLimits(t *testing.T) {
+	interp := lars.New(lars.WithLimits(lars.Limits{MaxSteps: 2000, Timeout: 200 * time.Millisecond}))
+	calls := 0
+	err := interp.RegisterFunc("repeat", func(ctx context.Context, n int, fn object.Object) error {
+		call := interp.Callable(fn)
+		for range n {
+			calls++
+			if _, err := call(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = interp.Eval(context.Background(), "repeat(5000, fn() { 1 + 1 })")
+	if !errors.Is(err, lars.ErrStepLimit) {
+		t.Fatalf("expected the callbacks to exhaust the step budget, got %v", err)
+	}
+	if calls >= 5000 {
+		t.Errorf("every callback got a fresh budget")
+	}
+
+	// the next evaluation starts afresh
+	if _, err := interp.Eval(context.Background(), "repeat(10, fn() { 1 + 1 })"); err != nil {
+		t.Errorf("limits carried over to the next evaluation: %v", err)
+	}
+}
+
+func TestCallableErrorStack(t *testing.T) {
+	interp := lars.New()
+	src := `let inner = fn(x) { x + true };
+let outer = fn(x) { inner(x) };
+let handler = fn() { outer(1) };`
+	if _, err := interp.Eval(context.Background(), src); err != nil {
+		t.Fatal(err)
+	}
+	handler, _ := interp.Get("handler")
+
+	_, err := interp.Callable(handler)(context.Background())
+	var runtimeErr *lars.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a RuntimeError, got %v", err)
+	}
+	if runtimeErr.Error() != "1:23: type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error %q", runtimeErr.Error())
+	}
+
+	want := []lars.Frame{
+		{Function: "inner", Line: 2, Col: 26},
+		{Function: "outer", Line: 3, Col: 27},
+		{Function: "handler"},
+	}
+	if !slices.Equal(runtimeErr.Stack, want) {
+		t.Errorf("wrong stack. want=%+v, got=%+v", want, runtimeErr.Stack)
+	}
+
+	trace := "\tat inner (2:26)\n\tat outer (3:27)\n\tat handler (called from Go)\n"
+	if runtimeErr.StackTrace() != trace {
+		t.Errorf("wrong stack trace. want=%q, got=%q", trace, runtimeErr.StackTrace())
+	}
+}
+
+// equalValues compares values converted by ToGo.
+func equalValues(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if !equalValues(v, b[k]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, equalValues)
+	}
+	return a == b
+}
@@ -0,0 +1,94 @@
+package lars
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/salty-max/lars/src/evaluator"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+)
+
+// Errors wrapped by a RuntimeError when evaluation exceeds its Limits.
+var (
+	ErrStepLimit  = evaluator.ErrStepLimit
+	ErrTimeout    = evaluator.ErrTimeout
+	ErrDepthLimit = evaluator.ErrDepthLimit
+	ErrAllocLimit = evaluator.ErrAllocLimit
+)
+
+// ParseError is returned when the source does not parse. It holds every
+// error the parser reported.
+type ParseError struct {
+	File   string // "" for source passed to Eval
+	Errors []parser.ParserError
+}
+
+func (e *ParseError) Error() string {
+	err := e.Errors[0]
+	msg := fmt.Sprintf("%s%d:%d: %s", filePrefix(e.File), err.Line, err.Col, err.Msg)
+	if len(e.Errors) > 1 {
+		msg += fmt.Sprintf(" (and %d more errors)", len(e.Errors)-1)
+	}
+	return msg
+}
+
+// Frame is a call of a lars function on the stack of a RuntimeError.
+type Frame = object.Frame
+
+// RuntimeError is returned when evaluation fails.
+type RuntimeError struct {
+	File  string // "" for source passed to Eval
+	Line  int    // 0 if the error has no position
+	Col   int
+	Msg   string
+	Hint  string  // optional help note, e.g. a spelling suggestion
+	Err   error   // the cause, such as ErrStepLimit, if there is one
+	Stack []Frame // the function calls the error unwound through, innermost first
+}
+
+func (e *RuntimeError) Error() string {
+	if e.Line == 0 {
+		return filePrefix(e.File) + e.Msg
+	}
+	return fmt.Sprintf("%s%d:%d: %s", filePrefix(e.File), e.Line, e.Col, e.Msg)
+}
+
+func (e *RuntimeError) Unwrap() error { return e.Err }
+
+// StackTrace formats the stack of the error, one call per line, innermost
+// first:
+//
+//	at inner (rules.lars:2:10)
+//	at handler (called from Go)
+func (e *RuntimeError) StackTrace() string {
+	var b strings.Builder
+	for _, frame := range e.Stack {
+		name := frame.Function
+		if name == "" {
+			name = "fn"
+		}
+		if frame.Line == 0 {
+			fmt.Fprintf(&b, "\tat %s (called from Go)\n", name)
+		} else {
+			fmt.Fprintf(&b, "\tat %s (%s%d:%d)\n", name, filePrefix(e.File), frame.Line, frame.Col)
+		}
+	}
+	return b.String()
+}
+
+// ExitError is returned when the program calls exit.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func filePrefix(file string) string {
+	if file == "" {
+		return ""
+	}
+	return file + ":"
+}
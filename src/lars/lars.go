@@ -0,0 +1,371 @@
+// Package lars embeds the lars interpreter in Go programs.
+//
+// An Interpreter keeps its global bindings between calls, so a host can
+// load a script once and then call the functions it defines:
+//
+//	interp := lars.New()
+//	if _, err := interp.EvalFile(ctx, "rules.lars"); err != nil {
+//		return err
+//	}
+//	allowed, err := interp.Call("allow", &object.String{Value: user})
+package lars
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/evaluator"
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+)
+
+// Interpreter evaluates lars programs in a persistent global environment.
+// Its methods, and the functions returned by Callable, may be used from
+// several goroutines: they take turns. Host functions run during an
+// evaluation must therefore not call Set, Get or RegisterFunc, and must
+// pass on the context they receive to call back into lars (see
+// RegisterFunc); such calls take turns with each other, from whichever
+// goroutine they are made, until the evaluation returns. Separate
+// interpreters share no mutable state and run in parallel.
+type Interpreter struct {
+	mu sync.Mutex
+	// running is the context of the evaluation holding mu, which host
+	// functions receive.
+	running context.Context
+
+	env    *object.Environment
+	logger *slog.Logger
+	limits Limits
+}
+
+// Limits bounds the resources a single Eval or Call may use; see
+// evaluator.Limits. A zero field means no limit, except that calls nest at
+// most DefaultMaxDepth deep unless MaxDepth says otherwise.
+type Limits = evaluator.Limits
+
+// DefaultMaxDepth is the call depth allowed when Limits.MaxDepth is zero.
+const DefaultMaxDepth = evaluator.DefaultMaxDepth
+
+// Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithLogger sets the logger the interpreter writes debug traces to: one
+// record when a script is parsed, evaluated or called by the host, and one
+// for each call it makes to a lars function or builtin. By default nothing
+// is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(i *Interpreter) {
+		i.logger = logger
+		i.env.SetLogger(logger)
+	}
+}
+
+// WithLimits sets the limits each Eval and Call runs under. Scripts from
+// untrusted sources should always be given limits. By default only the
+// call depth is limited, to DefaultMaxDepth.
+func WithLimits(limits Limits) Option {
+	return func(i *Interpreter) { i.limits = limits }
+}
+
+// WithGrants sandboxes the interpreter: scripts may only use the builtins
+// with side effects that grants allows, and get a permission denied error
+// naming the missing capability otherwise. See object.ParseGrants. By
+// default scripts may use every builtin.
+func WithGrants(grants *object.Grants) Option {
+	return func(i *Interpreter) { i.env.SetGrants(grants) }
+}
+
+// Pure sandboxes the interpreter so that scripts can have no side effects
+// at all: they cannot read files, the environment, the clock, random
+// numbers or input, nor write output. Functions registered by the host are not
+// restricted.
+func Pure() Option {
+	return WithGrants(object.NewGrants())
+}
+
+// WithStdout sets the writer that scripts print to. By default it is
+// os.Stdout. Wrap a shared writer with NewLineWriter, or NewPrefixWriter to
+// tell tenants apart, to keep lines from different interpreters whole.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.env.SetStdout(w) }
+}
+
+// WithStderr is WithStdout for error output, os.Stderr by default.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) { i.env.SetStderr(w) }
+}
+
+// WithStdin sets the reader that scripts read input from. By default it is
+// os.Stdin. The interpreter buffers it, so it should not be read from
+// elsewhere.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) { i.env.SetStdin(r) }
+}
+
+// New creates an interpreter with an empty global environment.
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{}
+	i.init(opts)
+	return i
+}
+
+// init gives i an empty global environment and the settings of opts.
+func (i *Interpreter) init(opts []Option) {
+	i.env = object.NewEnvironment()
+	i.logger = slog.New(discardHandler{})
+	i.limits = Limits{}
+
+	i.env.SetStdout(os.Stdout)
+	i.env.SetStderr(os.Stderr)
+	i.env.SetStdin(os.Stdin)
+	for _, opt := range opts {
+		opt(i)
+	}
+}
+
+// Eval evaluates src and returns the value of its last statement, which is
+// nil for statements without a value such as let. Failures are reported
+// as *ParseError, *RuntimeError or *ExitError. Evaluation stops when ctx
+// is done or a limit set with WithLimits is exceeded; the RuntimeError
+// then wraps ctx.Err() or one of ErrStepLimit, ErrTimeout, ErrDepthLimit
+// and ErrAllocLimit.
+func (i *Interpreter) Eval(ctx context.Context, src string) (object.Object, error) {
+	return i.eval(ctx, "", src)
+}
+
+// EvalFile evaluates the file at path like Eval. Errors are reported
+// against path.
+func (i *Interpreter) EvalFile(ctx context.Context, path string) (object.Object, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return i.eval(ctx, path, string(src))
+}
+
+func (i *Interpreter) eval(ctx context.Context, file, src string) (_ object.Object, err error) {
+	defer recoverPanic(file, &err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, leave := i.enter(ctx)
+	defer leave()
+
+	start := time.Now()
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{File: file, Errors: p.Errors()}
+	}
+	i.logger.DebugContext(ctx, "parsed", "file", file, "statements", len(program.Statements), "elapsed", time.Since(start))
+
+	return i.evalProgram(ctx, file, program)
+}
+
+// evalProgram evaluates the parsed program from file.
+func (i *Interpreter) evalProgram(ctx context.Context, file string, program *ast.Program) (object.Object, error) {
+	start := time.Now()
+	result := evaluator.EvalContext(ctx, program, i.env, i.limits)
+	i.logger.DebugContext(ctx, "evaluated", "file", file, "elapsed", time.Since(start))
+
+	return toResult(file, result)
+}
+
+// Set binds name to value in the global environment.
+func (i *Interpreter) Set(name string, value object.Object) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.env.Set(name, value)
+}
+
+// Get returns the global binding of name.
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.env.Get(name)
+}
+
+// Call calls the global function fnName with args.
+func (i *Interpreter) Call(fnName string, args ...object.Object) (object.Object, error) {
+	return i.CallContext(context.Background(), fnName, args...)
+}
+
+// CallContext is Call under ctx and the interpreter limits, like Eval.
+func (i *Interpreter) CallContext(ctx context.Context, fnName string, args ...object.Object) (_ object.Object, err error) {
+	defer recoverPanic("", &err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, leave := i.enter(ctx)
+	defer leave()
+
+	fn, ok := i.env.Get(fnName)
+	if !ok {
+		return nil, fmt.Errorf("lars: function %s is not defined", fnName)
+	}
+	switch fn.(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return nil, fmt.Errorf("lars: %s is not a function: %s", fnName, fn.Type())
+	}
+
+	i.logger.DebugContext(ctx, "call", "fn", fnName, "args", len(args))
+	return toResult("", evaluator.ApplyContext(ctx, i.env, i.limits, fn, args...))
+}
+
+// Callable returns a Go function that calls fn, a lars function or
+// builtin, such as an event handler or a comparator that a script handed
+// to a host function. Functions run in the environment they were defined
+// in, under ctx and the interpreter limits like CallContext. Arguments are
+// converted with object.FromGo, and the result with object.ToGo into an
+// any; values with no Go equivalent, such as functions, are returned as
+// object.Object. Errors raised by fn are returned as *RuntimeError, with
+// the stack of lars calls.
+//
+// The returned function may be called from any goroutine, but not after
+// the interpreter is put back in a Pool.
+func (i *Interpreter) Callable(fn object.Object) func(ctx context.Context, args ...any) (any, error) {
+	return func(ctx context.Context, args ...any) (_ any, err error) {
+		defer recoverPanic("", &err)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		env := i.env
+		switch f := fn.(type) {
+		case *object.Function:
+			env = f.Env
+		case *object.Builtin:
+		case nil:
+			return nil, fmt.Errorf("lars: Callable of nil")
+		default:
+			return nil, fmt.Errorf("lars: %s is not a function", fn.Type())
+		}
+
+		in := make([]object.Object, len(args))
+		for n, arg := range args {
+			obj, err := object.FromGo(arg)
+			if err != nil {
+				return nil, fmt.Errorf("lars: argument %d %s", n+1, describe(err))
+			}
+			in[n] = obj
+		}
+
+		ctx, leave := i.enter(ctx)
+		defer leave()
+
+		result, err := toResult("", evaluator.ApplyContext(ctx, env, i.limits, fn, in...))
+		if err != nil || result == nil {
+			return nil, err
+		}
+		var out any
+		if err := object.ToGo(result, &out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+}
+
+// evaluation is an Eval, Call or call of a callable that holds the
+// interpreter lock, along with the calls back into lars nested in it.
+type evaluation struct {
+	i *Interpreter
+	// turn is held while lars code runs. The host functions it calls give
+	// it up, so that calls back into lars made with the context they
+	// receive, from any goroutine, take turns with each other and with the
+	// evaluation.
+	turn sync.Mutex
+	done bool // set under turn once the evaluation has returned
+}
+
+// evaluationKey is the key of the *evaluation in the context of the
+// evaluation.
+type evaluationKey struct{}
+
+// enter locks the interpreter for an evaluation under ctx, and returns the
+// context to run it under and the function that unlocks the interpreter.
+// If ctx is the context of an evaluation that is still running, as when a
+// host function calls back into lars, the call is nested in it instead:
+// it waits for its turn rather than for the interpreter lock, which the
+// evaluation holds.
+func (i *Interpreter) enter(ctx context.Context) (context.Context, func()) {
+	if ev, ok := ctx.Value(evaluationKey{}).(*evaluation); ok && ev.i == i {
+		ev.turn.Lock()
+		if !ev.done {
+			return ctx, ev.turn.Unlock
+		}
+		ev.turn.Unlock()
+	}
+
+	i.mu.Lock()
+	ev := &evaluation{i: i}
+	ev.turn.Lock()
+	i.running = context.WithValue(ctx, evaluationKey{}, ev)
+	return i.running, func() {
+		ev.done = true
+		i.running = nil
+		ev.turn.Unlock()
+		i.mu.Unlock()
+	}
+}
+
+// outside runs f, a host function called by the running evaluation, with
+// the context of the evaluation. The evaluation gives up its turn
+// meanwhile, so that f can call back into lars with the context.
+func (i *Interpreter) outside(f func(ctx context.Context)) {
+	ctx := i.running
+	if ctx == nil {
+		f(context.Background())
+		return
+	}
+
+	ev := ctx.Value(evaluationKey{}).(*evaluation)
+	ev.turn.Unlock()
+	defer ev.turn.Lock()
+	f(ctx)
+}
+
+// recoverPanic is deferred by the entry points of the interpreter. It turns
+// a panic in the evaluator or in a host function into a *RuntimeError, so
+// that a script cannot crash the host.
+func recoverPanic(file string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	*err = &RuntimeError{File: file, Msg: fmt.Sprintf("internal error: %v", r), Err: fmt.Errorf("panic: %v", r)}
+}
+
+// toResult turns the errors and exits evaluation produces into Go errors.
+func toResult(file string, result object.Object) (object.Object, error) {
+	switch result := result.(type) {
+	case *object.Error:
+		return nil, &RuntimeError{
+			File:  file,
+			Line:  result.Line,
+			Col:   result.Col,
+			Msg:   result.Message,
+			Hint:  result.Hint,
+			Err:   result.Err,
+			Stack: result.Stack,
+		}
+	case *object.Exit:
+		return nil, &ExitError{Code: result.Code}
+	}
+	return result, nil
+}
+
+// discardHandler is a slog.Handler that drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,296 @@
+package lars_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/salty-max/lars/src/lars"
+	"github.com/salty-max/lars/src/object"
+)
+
+func TestEval(t *testing.T) {
+	interp := lars.New()
+
+	if _, err := interp.Eval(context.Background(), "let x = 20;"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := interp.Eval(context.Background(), "x * 2 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Inspect() != "42" {
+		t.Errorf("wrong result. want=42, got=%s", result.Inspect())
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	interp := lars.New()
+
+	_, err := interp.Eval(context.Background(), "let = 1; let = 2;")
+	var parseErr *lars.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a *ParseError, got=%T (%v)", err, err)
+	}
+	if err.Error() != "1:5: expected next token to be IDENT, got = instead (and 1 more errors)" {
+		t.Errorf("wrong message %q", err.Error())
+	}
+
+	_, err = interp.Eval(context.Background(), "let count = 1; cont")
+	var runtimeErr *lars.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a *RuntimeError, got=%T (%v)", err, err)
+	}
+	if runtimeErr.Line != 1 || runtimeErr.Col != 16 || runtimeErr.Hint != "did you mean `count`?" {
+		t.Errorf("wrong runtime error %+v", runtimeErr)
+	}
+
+	_, err = interp.Eval(context.Background(), "exit(3)")
+	var exitErr *lars.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Errorf("expected exit status 3, got=%v", err)
+	}
+}
+
+func TestPanicsAreRecovered(t *testing.T) {
+	interp := lars.New()
+	interp.Set("boom", &object.Builtin{Name: "boom", Fn: func(*object.Environment, ...object.Object) object.Object {
+		panic("kaboom")
+	}})
+
+	for _, src := range []string{"1 / 0", "5 % 0"} {
+		if _, err := interp.Eval(context.Background(), src); err == nil || !strings.HasSuffix(err.Error(), "division by zero") {
+			t.Errorf("%s: expected a division by zero error, got %v", src, err)
+		}
+	}
+
+	check := func(what string, err error) {
+		t.Helper()
+		var runtimeErr *lars.RuntimeError
+		if !errors.As(err, &runtimeErr) || runtimeErr.Msg != "internal error: kaboom" {
+			t.Errorf("%s: expected the panic as a RuntimeError, got %v", what, err)
+		}
+	}
+	_, err := interp.Eval(context.Background(), "boom()")
+	check("Eval", err)
+	_, err = interp.Call("boom")
+	check("Call", err)
+	boom, _ := interp.Get("boom")
+	_, err = interp.Callable(boom)(context.Background())
+	check("Callable", err)
+
+	// the interpreter is still usable
+	if result, err := interp.Eval(context.Background(), "1 + 1"); err != nil || result.Inspect() != "2" {
+		t.Errorf("interpreter broken after a panic: %v, %v", result, err)
+	}
+}
+
+func TestEvalCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := lars.New().Eval(ctx, "1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got=%v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	interp := lars.New(lars.WithLimits(lars.Limits{MaxSteps: 500, MaxDepth: 20}))
+
+	_, err := interp.Eval(context.Background(), "let f = fn(n) { f(n + 1) };\nf(0)")
+	var runtimeErr *lars.RuntimeError
+	if !errors.As(err, &runtimeErr) || !errors.Is(err, lars.ErrDepthLimit) {
+		t.Fatalf("expected a depth limit RuntimeError, got %T: %v", err, err)
+	}
+	if want := "1:18: maximum call depth exceeded (20 calls)"; err.Error() != want {
+		t.Errorf("wrong error. want=%q, got=%q", want, err.Error())
+	}
+
+	if _, err := interp.Eval(context.Background(), "let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };"); err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if _, err := interp.Call("fib", &object.Integer{Value: 20}); !errors.Is(err, lars.ErrStepLimit) {
+		t.Errorf("expected the step limit to apply to Call, got %v", err)
+	}
+	result, err := interp.CallContext(context.Background(), "fib", &object.Integer{Value: 5})
+	if err != nil || result.Inspect() != "5" {
+		t.Errorf("CallContext = %v, %v; want 5", result, err)
+	}
+}
+
+func TestDefaultDepthLimit(t *testing.T) {
+	_, err := lars.New().Eval(context.Background(), "let f = fn(n) { f(n + 1) };\nf(0)")
+	if !errors.Is(err, lars.ErrDepthLimit) || !strings.HasSuffix(err.Error(), fmt.Sprintf("(%d calls)", lars.DefaultMaxDepth)) {
+		t.Errorf("expected runaway recursion to stop at the default depth, got %v", err)
+	}
+
+	unlimited := lars.New(lars.WithLimits(lars.Limits{MaxDepth: -1}))
+	result, err := unlimited.Eval(context.Background(), "let f = fn(n) { if (n > 0) { f(n - 1) } else { n } };\nf(12000)")
+	if err != nil || result.Inspect() != "0" {
+		t.Errorf("a negative MaxDepth should lift the limit, got %v, %v", result, err)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	pure := lars.New(lars.Pure())
+	_, err := pure.Eval(context.Background(), "now()")
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if want := "1:4: permission denied: `now` needs the clock capability"; err.Error() != want {
+		t.Errorf("wrong error. want=%q, got=%q", want, err.Error())
+	}
+
+	grants, err := object.ParseGrants("clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := lars.New(lars.WithGrants(grants))
+	if _, err := clock.Eval(context.Background(), "now()"); err != nil {
+		t.Errorf("now() with the clock capability failed: %v", err)
+	}
+	if _, err := clock.Eval(context.Background(), "random()"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected random() to be denied, got %v", err)
+	}
+}
+
+func TestEvalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.lars")
+	if err := os.WriteFile(path, []byte("let limit = 10;\n1 + true"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	interp := lars.New()
+	_, err := interp.EvalFile(context.Background(), path)
+	if err == nil || err.Error() != path+":2:3: type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error %v", err)
+	}
+	if _, ok := interp.Get("limit"); !ok {
+		t.Errorf("bindings made before the error should be kept")
+	}
+
+	if _, err := interp.EvalFile(context.Background(), path+".missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-exist error, got=%v", err)
+	}
+}
+
+func TestSetGetAndCall(t *testing.T) {
+	interp := lars.New()
+	interp.Set("greeting", &object.String{Value: "hello"})
+
+	_, err := interp.Eval(context.Background(), `let greet = fn(name) { greeting + ", " + name };`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := interp.Call("greet", &object.String{Value: "lars"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inspect() != "hello, lars" {
+		t.Errorf("wrong result %q", result.Inspect())
+	}
+
+	if _, err := interp.Call("greet"); err == nil || !strings.Contains(err.Error(), "wrong number of arguments") {
+		t.Errorf("expected an arity error, got=%v", err)
+	}
+	if _, err := interp.Call("missing"); err == nil {
+		t.Errorf("expected an error calling an undefined function")
+	}
+	if _, err := interp.Call("greeting"); err == nil {
+		t.Errorf("expected an error calling a string")
+	}
+	if got, ok := interp.Get("greeting"); !ok || got.Inspect() != "hello" {
+		t.Errorf("wrong binding %v", got)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	src := "let double = fn(x) { x * 2 };\nlen(str(double(1)))"
+	if _, err := lars.New(lars.WithLogger(logger)).Eval(context.Background(), src); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"msg=parsed",
+		"msg=function fn=double args=1 line=2 col=15",
+		"msg=builtin fn=str args=1 line=2 col=8",
+		"msg=builtin fn=len args=1 line=2 col=4",
+		"msg=evaluated",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing debug trace %q in %q", want, buf.String())
+		}
+	}
+}
+
+func TestStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := lars.New(
+		lars.WithStdout(&stdout),
+		lars.WithStderr(&stderr),
+		lars.WithStdin(strings.NewReader("first\nsecond\n")),
+	)
+
+	src := `puts(input()); let f = fn() { puts(input()) }; f(); eputs("careful"); puts("done")`
+	if _, err := interp.Eval(context.Background(), src); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "first\nsecond\ndone\n" {
+		t.Errorf("wrong stdout %q", stdout.String())
+	}
+	if stderr.String() != "careful\n" {
+		t.Errorf("wrong stderr %q", stderr.String())
+	}
+}
+
+func TestInterpretersInParallel(t *testing.T) {
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var stdout bytes.Buffer
+			interp := lars.New(
+				lars.WithStdout(&stdout),
+				lars.WithLimits(lars.Limits{MaxSteps: 50_000, MaxDepth: 64}),
+			)
+			interp.Set("id", &object.Integer{Value: int64(g)})
+			if err := interp.RegisterFunc("double", func(n int) int { return 2 * n }); err != nil {
+				t.Error(err)
+				return
+			}
+
+			for n := range 30 {
+				src := fmt.Sprintf(`
+let state = {"id": id, "seen": [0, 0]};
+let add = fn(i, x) { state.seen[i] = x };
+add(0, %d); add(1, double(id));
+[state.id, state.seen[0], state.seen[1], true, null]`, n)
+				result, err := interp.Eval(context.Background(), src)
+				want := fmt.Sprintf("[%d, %d, %d, true, null]", g, n, 2*g)
+				if err != nil || result.Inspect() != want {
+					t.Errorf("interpreter %d: want=%s, got=%v, %v", g, want, result, err)
+				}
+
+				if _, err := interp.Eval(context.Background(), "let f = fn() { f() }; f()"); !errors.Is(err, lars.ErrDepthLimit) {
+					t.Errorf("interpreter %d: expected a depth error, got %v", g, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,106 @@
+package lars
+
+import (
+	"context"
+	"sync"
+
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+)
+
+// preludeFile is the name errors in a pool prelude are reported against.
+const preludeFile = "prelude"
+
+// Pool hands out interpreters that have already evaluated a prelude, such
+// as a library of functions shared by many short scripts. The prelude is
+// evaluated once; each interpreter starts from a copy of the globals it
+// left, which is much cheaper than evaluating it again. A Pool is safe for
+// concurrent use.
+//
+//	pool, err := lars.NewPool(8, rules, lars.Pure())
+//	...
+//	interp := pool.Get()
+//	defer pool.Put(interp)
+//	allowed, err := interp.Call("allow", &object.String{Value: user})
+type Pool struct {
+	size int
+	opts []Option
+	// globals is the environment the prelude left, which is never
+	// evaluated in again, only copied.
+	globals *object.Environment
+
+	mu   sync.Mutex
+	idle []*Interpreter
+}
+
+// NewPool creates a pool of interpreters configured with opts, in which
+// prelude has been evaluated. It prepares size interpreters up front, and
+// keeps at most size of them idle. The prelude runs under the limits set
+// in opts; if it fails, NewPool returns the *ParseError or *RuntimeError,
+// reported against the file name "prelude". Since it runs only once, every
+// interpreter sees the same results of builtins such as random and now.
+func NewPool(size int, prelude string, opts ...Option) (*Pool, error) {
+	p := parser.New(lexer.New(prelude))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{File: preludeFile, Errors: p.Errors()}
+	}
+
+	warm := New(opts...)
+	if _, err := warm.evalProgram(context.Background(), preludeFile, program); err != nil {
+		return nil, err
+	}
+
+	pool := &Pool{size: size, opts: opts, globals: warm.env}
+	for range size {
+		i := &Interpreter{}
+		pool.reset(i)
+		pool.idle = append(pool.idle, i)
+	}
+	return pool, nil
+}
+
+// Get returns an idle interpreter, or prepares a new one if there is none.
+func (p *Pool) Get() *Interpreter {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		i := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return i
+	}
+	p.mu.Unlock()
+
+	i := &Interpreter{}
+	p.reset(i)
+	return i
+}
+
+// Put returns i, which came from Get, to the pool. Nothing the scripts run
+// in i did carries over: its globals, including functions registered with
+// RegisterFunc, are reset to those the prelude left. i must not be used
+// after Put.
+func (p *Pool) Put(i *Interpreter) {
+	p.mu.Lock()
+	full := len(p.idle) >= p.size
+	p.mu.Unlock()
+	if full {
+		return
+	}
+
+	p.reset(i)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) < p.size {
+		p.idle = append(p.idle, i)
+	}
+}
+
+// reset gives i the pool options and a copy of the globals the prelude
+// left.
+func (p *Pool) reset(i *Interpreter) {
+	i.init(p.opts)
+	p.globals.CopyTo(i.env)
+}
@@ -0,0 +1,140 @@
+package lars_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/salty-max/lars/src/lars"
+	"github.com/salty-max/lars/src/object"
+)
+
+const prelude = `
+let counter = {"count": 0};
+let bump = fn() { counter.count = counter.count + 1 };
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+let counting = fn() { let state = {"n": 0}; fn() { state.n = state.n + 1 } };
+let tick = counting();
+let greet = fn() { "hello, " + name };
+let name = "prelude";
+`
+
+func TestPool(t *testing.T) {
+	pool, err := lars.NewPool(1, prelude)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interp := pool.Get()
+	if _, err := interp.Eval(context.Background(), "let leaked = 1; bump(); bump(); tick(); tick();"); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := interp.Eval(context.Background(), "counter.count"); count.Inspect() != "2" {
+		t.Errorf("wrong count %s", count.Inspect())
+	}
+	pool.Put(interp)
+
+	// the interpreter is reused, with nothing left of the previous script
+	again := pool.Get()
+	if again != interp {
+		t.Errorf("idle interpreter not reused")
+	}
+	if _, ok := again.Get("leaked"); ok {
+		t.Errorf("global leaked into the next use of the interpreter")
+	}
+	if count, _ := again.Eval(context.Background(), "counter.count"); count.Inspect() != "0" {
+		t.Errorf("prelude state leaked into the next use: count is %s", count.Inspect())
+	}
+	if n, _ := again.Eval(context.Background(), "tick()"); n.Inspect() != "1" {
+		t.Errorf("closure state leaked into the next use: tick() is %s", n.Inspect())
+	}
+	// prelude functions see the globals of the interpreter they run in
+	if hello, _ := again.Eval(context.Background(), `let name = "script"; greet()`); hello.Inspect() != "hello, script" {
+		t.Errorf("wrong greeting %s", hello.Inspect())
+	}
+
+	// an empty pool prepares new interpreters
+	other := pool.Get()
+	if result, err := other.Call("fib", &object.Integer{Value: 10}); err != nil || result.Inspect() != "55" {
+		t.Errorf("fib(10) = %v, %v", result, err)
+	}
+	pool.Put(other)
+	pool.Put(again)
+}
+
+func TestPoolPreludeErrors(t *testing.T) {
+	var parseErr *lars.ParseError
+	if _, err := lars.NewPool(1, "let = 1"); !errors.As(err, &parseErr) || parseErr.File != "prelude" {
+		t.Errorf("expected a parse error in the prelude, got %v", err)
+	}
+
+	var runtimeErr *lars.RuntimeError
+	if _, err := lars.NewPool(1, "1 + true"); !errors.As(err, &runtimeErr) || runtimeErr.File != "prelude" {
+		t.Errorf("expected a runtime error in the prelude, got %v", err)
+	}
+
+	if _, err := lars.NewPool(1, "now()", lars.Pure()); err == nil {
+		t.Errorf("expected the prelude to run under the pool options")
+	}
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	pool, err := lars.NewPool(4, prelude, lars.WithLimits(lars.Limits{MaxSteps: 100_000}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range 30 {
+				interp := pool.Get()
+
+				src := fmt.Sprintf("let mine = %d; bump(); bump(); [mine, counter.count, fib(%d)]", g, n%12)
+				result, err := interp.Eval(context.Background(), src)
+				want := fmt.Sprintf("[%d, 2, %d]", g, fibonacci(n%12))
+				if err != nil || result.Inspect() != want {
+					t.Errorf("goroutine %d: want=%s, got=%v, %v", g, want, result, err)
+				}
+				pool.Put(interp)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func fibonacci(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fibonacci(n-1) + fibonacci(n-2)
+}
+
+// library is a prelude that takes a while to evaluate.
+const library = prelude + `
+let ids = fn(n) { if (n == 1) { [0] } else { let xs = ids(n / 2); flatten([xs, map(xs, fn(x) { x + len(xs) })]) } };
+let table = map(ids(2048), fn(i) { {"id": i, "square": i * i, "name": "item " + str(i)} });
+let index = reduce(table, fn(acc, row) { acc[row.name] = row; acc }, {});
+`
+
+func BenchmarkPoolGetPut(b *testing.B) {
+	pool, err := lars.NewPool(1, library)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for range b.N {
+		pool.Put(pool.Get())
+	}
+}
+
+func BenchmarkEvalPrelude(b *testing.B) {
+	for range b.N {
+		if _, err := lars.New().Eval(context.Background(), library); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
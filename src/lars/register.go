@@ -0,0 +1,154 @@
+package lars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/salty-max/lars/src/object"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// RegisterFunc binds name to a builtin that calls fn, which must be a Go
+// function. Arguments are converted from lars values to the parameter
+// types with object.ToGo, so any type it decodes into is supported, and
+// object.Object parameters receive the value as is. Variadic functions
+// take any number of trailing arguments.
+//
+// fn may return nothing, a value, an error, or a value and an error. The
+// value is converted back to a lars value with object.FromGo; a non-nil
+// error becomes a lars runtime error.
+//
+// If the first parameter of fn is a context.Context, it receives the
+// context of the Eval or Call running the script. fn must pass it on to
+// the functions returned by Callable to call back into lars before it
+// returns, as a comparator would be. It may do so from other goroutines;
+// the calls take turns. Once the evaluation has returned, the context no
+// longer lets a call in ahead of other evaluations.
+func (i *Interpreter) RegisterFunc(name string, fn any) error {
+	builtin, err := newBuiltin(name, fn, i.outside)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.env.Set(name, builtin)
+	return nil
+}
+
+// newBuiltin wraps fn in a builtin. run calls fn with the context to pass
+// it if it takes one.
+func newBuiltin(name string, fn any, run func(func(ctx context.Context))) (*object.Builtin, error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("lars: RegisterFunc %s: %T is not a function", name, fn)
+	}
+
+	ft := fv.Type()
+	switch {
+	case ft.NumOut() > 2:
+		return nil, fmt.Errorf("lars: RegisterFunc %s: too many results", name)
+	case ft.NumOut() == 2 && ft.Out(1) != errorType:
+		return nil, fmt.Errorf("lars: RegisterFunc %s: second result must be an error", name)
+	}
+
+	call := func(_ *object.Environment, args ...object.Object) (result object.Object) {
+		defer func() {
+			if r := recover(); r != nil {
+				err, _ := r.(error)
+				result = &object.Error{Message: fmt.Sprintf("%s: panic: %v", name, r), Err: err}
+			}
+		}()
+
+		takesContext := ft.NumIn() > 0 && ft.In(0) == contextType
+		skip := 0
+		if takesContext {
+			skip = 1
+		}
+		in, errObj := convertArgs(name, ft, skip, args)
+		if errObj != nil {
+			return errObj
+		}
+
+		var out []reflect.Value
+		run(func(ctx context.Context) {
+			if takesContext {
+				in = append([]reflect.Value{reflect.ValueOf(ctx)}, in...)
+			}
+			out = fv.Call(in)
+		})
+		return convertResults(name, out)
+	}
+
+	return &object.Builtin{Name: name, Fn: call}, nil
+}
+
+// convertArgs converts args to the parameter types of ft, after the first
+// skip parameters.
+func convertArgs(name string, ft reflect.Type, skip int, args []object.Object) ([]reflect.Value, *object.Error) {
+	fixed := ft.NumIn() - skip
+	if ft.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return nil, &object.Error{Message: fmt.Sprintf(
+				"wrong number of arguments: want at least %d, got=%d", fixed, len(args))}
+		}
+	} else if len(args) != fixed {
+		return nil, &object.Error{Message: fmt.Sprintf(
+			"wrong number of arguments: want=%d, got=%d", fixed, len(args))}
+	}
+
+	in := make([]reflect.Value, len(args))
+	for n, arg := range args {
+		t := ft.In(min(skip+n, ft.NumIn()-1))
+		if n >= fixed && ft.IsVariadic() {
+			t = t.Elem()
+		}
+
+		v := reflect.New(t)
+		if err := object.ToGo(arg, v.Interface()); err != nil {
+			return nil, &object.Error{Message: fmt.Sprintf("argument %d to `%s` %s", n+1, name, describe(err))}
+		}
+		in[n] = v.Elem()
+	}
+
+	return in, nil
+}
+
+// convertResults converts the results of a registered function back to a
+// lars value.
+func convertResults(name string, out []reflect.Value) object.Object {
+	if len(out) > 0 && out[len(out)-1].Type() == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return &object.Error{Message: fmt.Sprintf("%s: %s", name, err), Err: err}
+		}
+		out = out[:len(out)-1]
+	}
+
+	if len(out) == 0 {
+		return object.NULL
+	}
+
+	obj, err := object.FromGo(out[0].Interface())
+	if err != nil {
+		return &object.Error{Message: fmt.Sprintf("result of `%s` %s", name, describe(err))}
+	}
+	return obj
+}
+
+// describe phrases a conversion error to follow the value it is about.
+func describe(err error) string {
+	var convErr *object.ConversionError
+	if !errors.As(err, &convErr) {
+		return err.Error()
+	}
+	if convErr.Path == "" {
+		return convErr.Msg
+	}
+	return "at " + convErr.Path + " " + convErr.Msg
+}
@@ -0,0 +1,171 @@
+package lars_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/salty-max/lars/src/lars"
+	"github.com/salty-max/lars/src/object"
+)
+
+func newInterpreter(t *testing.T, funcs map[string]any) *lars.Interpreter {
+	t.Helper()
+
+	interp := lars.New()
+	for name, fn := range funcs {
+		if err := interp.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("RegisterFunc(%q) failed: %v", name, err)
+		}
+	}
+	return interp
+}
+
+func TestRegisterFunc(t *testing.T) {
+	interp := newInterpreter(t, map[string]any{
+		"add":   func(a, b int) int { return a + b },
+		"scale": func(x float64, by float32) float64 { return x * float64(by) },
+		"not":   func(b bool) bool { return !b },
+		"upper": strings.ToUpper,
+		"sum": func(xs ...int) (total int) {
+			for _, x := range xs {
+				total += x
+			}
+			return
+		},
+		"join": func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+		"keys": func(m map[string]int) []string {
+			keys := []string{}
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return keys
+		},
+		"count": func(m map[string][]bool) map[string]int {
+			c := map[string]int{}
+			for k, v := range m {
+				c[k] = len(v)
+			}
+			return c
+		},
+		"typeOf":  func(v any) string { return fmt.Sprintf("%T", v) },
+		"raw":     func(obj object.Object) string { return string(obj.Type()) },
+		"noop":    func() {},
+		"nothing": func() []int { return nil },
+		"ptr":     func() *int { n := 7; return &n },
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(2, 3)", "5"},
+		{"scale(1.5, 2)", "3.000000"},
+		{"not(false)", "true"},
+		{"if (not(true)) { 1 } else { 2 }", "2"},
+		{`upper("lars")`, "LARS"},
+		{"sum()", "0"},
+		{"sum(1, 2, 3)", "6"},
+		{`join("-", "a", "b", "c")`, "a-b-c"},
+		{`join(",")`, ""},
+		{`keys({"b": 1, "a": 2})`, "[a, b]"},
+		{`count({"x": [true, false]})["x"]`, "2"},
+		{"typeOf(1)", "int64"},
+		{"typeOf(1.5)", "float64"},
+		{`typeOf([1, "a"])`, "[]interface {}"},
+		{`typeOf({1: 2})`, "map[interface {}]interface {}"},
+		{"typeOf(null)", "<nil>"},
+		{"raw(fn() {})", "FUNCTION"},
+		{"noop()", "null"},
+		{"nothing()", "null"},
+		{"ptr()", "7"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Eval(context.Background(), tt.input)
+		if err != nil {
+			t.Errorf("input %q: unexpected error %v", tt.input, err)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. want=%q, got=%q", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestRegisterFuncErrors(t *testing.T) {
+	interp := newInterpreter(t, map[string]any{
+		"add":      func(a, b int) int { return a + b },
+		"small":    func(n int8) int8 { return n },
+		"unsigned": func(n uint) uint { return n },
+		"sum":      func(first int, rest ...int) int { return first },
+		"ints":     func(xs []int) int { return len(xs) },
+		"lookup":   func(key string) (string, error) { return "", errors.New("no such key") },
+		"boom":     func() int { panic("kaboom") },
+		"chan":     func() chan int { return nil },
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(1)", "1:4: wrong number of arguments: want=2, got=1"},
+		{`add(1, "2")`, "1:4: argument 2 to `add` must be INTEGER, got STRING"},
+		{"small(300)", "1:6: argument 1 to `small` overflows int8: 300"},
+		{"unsigned(-1)", "1:9: argument 1 to `unsigned` overflows uint: -1"},
+		{"sum()", "1:4: wrong number of arguments: want at least 1, got=0"},
+		{"sum(1, 2, true)", "1:4: argument 3 to `sum` must be INTEGER, got BOOLEAN"},
+		{`ints([1, "a"])`, "1:5: argument 1 to `ints` at [1] must be INTEGER, got STRING"},
+		{`lookup("x")`, "1:7: lookup: no such key"},
+		{"boom()", "1:5: boom: panic: kaboom"},
+		{"chan()", "1:5: result of `chan` cannot be converted from chan int"},
+	}
+
+	for _, tt := range tests {
+		_, err := interp.Eval(context.Background(), tt.input)
+		var runtimeErr *lars.RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("input %q: expected a *RuntimeError, got=%v", tt.input, err)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+// errNoKey is a host error that scripts pass back to the host.
+var errNoKey = errors.New("no such key")
+
+func TestRegisterFuncErrorsUnwrap(t *testing.T) {
+	interp := newInterpreter(t, map[string]any{
+		"lookup": func(key string) (string, error) { return "", fmt.Errorf("%s: %w", key, errNoKey) },
+		"boom":   func() int { panic(errNoKey) },
+	})
+
+	for _, input := range []string{`lookup("x")`, "boom()"} {
+		_, err := interp.Eval(context.Background(), input)
+		if !errors.Is(err, errNoKey) {
+			t.Errorf("input %q: errors.Is(%v, errNoKey) = false", input, err)
+		}
+	}
+}
+
+func TestRegisterFuncRejectsBadSignatures(t *testing.T) {
+	interp := lars.New()
+
+	for _, fn := range []any{
+		42,
+		(func())(nil),
+		func() (int, int) { return 0, 0 },
+		func() (int, error, bool) { return 0, nil, false },
+	} {
+		if err := interp.RegisterFunc("bad", fn); err == nil {
+			t.Errorf("RegisterFunc(%T) should fail", fn)
+		}
+	}
+}
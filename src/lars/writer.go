@@ -0,0 +1,82 @@
+package lars
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// LineWriter buffers what is written to it and passes it on one whole line
+// at a time, each line in a single Write. Interpreters that share a log can
+// each write through their own LineWriter, so that their output interleaves
+// by lines rather than mid-line. The underlying writer must then be safe
+// for concurrent use, as an *os.File is.
+//
+// A LineWriter is safe for concurrent use. Call Flush when done to write
+// out a last line without a newline.
+type LineWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+// NewLineWriter returns a LineWriter writing to w.
+func NewLineWriter(w io.Writer) *LineWriter {
+	return &LineWriter{w: w}
+}
+
+// NewPrefixWriter returns a LineWriter writing to w that starts each line
+// with prefix, such as "[tenant-42] ".
+func NewPrefixWriter(w io.Writer, prefix string) *LineWriter {
+	return &LineWriter{w: w, prefix: []byte(prefix)}
+}
+
+// Write writes out the lines p completes, and buffers the rest. If writing
+// a line fails, it returns the number of bytes of p in the lines written
+// before, and keeps the partial line buffered.
+func (l *LineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := 0
+	for {
+		i := bytes.IndexByte(p[n:], '\n')
+		if i < 0 {
+			break
+		}
+		if err := l.writeLine(p[n : n+i+1]); err != nil {
+			return n, err
+		}
+		n += i + 1
+	}
+	l.buf = append(l.buf, p[n:]...)
+
+	return len(p), nil
+}
+
+// Flush writes out the buffered partial line, if any.
+func (l *LineWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) == 0 {
+		return nil
+	}
+	return l.writeLine(nil)
+}
+
+// writeLine writes the prefix, the buffered partial line and then end. The
+// buffer is emptied once the line is written.
+func (l *LineWriter) writeLine(end []byte) error {
+	line := make([]byte, 0, len(l.prefix)+len(l.buf)+len(end))
+	line = append(line, l.prefix...)
+	line = append(line, l.buf...)
+	line = append(line, end...)
+
+	if _, err := l.w.Write(line); err != nil {
+		return err
+	}
+	l.buf = l.buf[:0]
+	return nil
+}
@@ -0,0 +1,121 @@
+package lars_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/salty-max/lars/src/lars"
+)
+
+// recorder records each Write as one entry.
+type recorder struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+func TestLineWriter(t *testing.T) {
+	var r recorder
+	w := lars.NewLineWriter(&r)
+
+	fmt.Fprint(w, "hel")
+	fmt.Fprint(w, "lo\nwor")
+	fmt.Fprint(w, "ld\n\nend")
+	if want := []string{"hello\n", "world\n", "\n"}; !slices.Equal(r.writes, want) {
+		t.Errorf("wrong writes before flush. want=%q, got=%q", want, r.writes)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hello\n", "world\n", "\n", "end"}; !slices.Equal(r.writes, want) {
+		t.Errorf("wrong writes after flush. want=%q, got=%q", want, r.writes)
+	}
+}
+
+// failing fails every Write after the first ok ones.
+type failing struct {
+	recorder
+	ok int
+}
+
+func (f *failing) Write(p []byte) (int, error) {
+	if len(f.writes) >= f.ok {
+		return 0, errors.New("disk full")
+	}
+	return f.recorder.Write(p)
+}
+
+func TestLineWriterErrors(t *testing.T) {
+	f := &failing{ok: 1}
+	w := lars.NewLineWriter(f)
+
+	n, err := w.Write([]byte("ab\ncd\nef"))
+	if err == nil || n != 3 {
+		t.Errorf("want the 3 bytes of the first line and an error, got %d, %v", n, err)
+	}
+
+	// the rest can be written again once the writer recovers
+	f.ok = 10
+	if n, err := w.Write([]byte("cd\nef\n")); err != nil || n != 6 {
+		t.Errorf("retry = %d, %v", n, err)
+	}
+	if want := []string{"ab\n", "cd\n", "ef\n"}; !slices.Equal(f.writes, want) {
+		t.Errorf("wrong writes. want=%q, got=%q", want, f.writes)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := lars.NewPrefixWriter(&buf, "[a] ")
+
+	fmt.Fprint(w, "one\ntw")
+	fmt.Fprint(w, "o\nthree")
+	w.Flush()
+
+	if want := "[a] one\n[a] two\n[a] three"; buf.String() != want {
+		t.Errorf("wrong output. want=%q, got=%q", want, buf.String())
+	}
+}
+
+func TestLineWritersShareAWriter(t *testing.T) {
+	var r recorder
+	var wg sync.WaitGroup
+	for _, tenant := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := lars.NewPrefixWriter(&r, tenant+": ")
+			for i := range 50 {
+				// split each line over two writes
+				fmt.Fprintf(w, "line ")
+				fmt.Fprintf(w, "%d\n", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(r.writes) != 150 {
+		t.Fatalf("wrong number of writes. want=150, got=%d", len(r.writes))
+	}
+	for _, line := range r.writes {
+		tenant, rest, ok := strings.Cut(line, ": ")
+		if !ok || !strings.Contains("abc", tenant) || !strings.HasPrefix(rest, "line ") || !strings.HasSuffix(rest, "\n") {
+			t.Errorf("garbled line %q", line)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package lexer
 
 import (
-	"fmt"
+	"strings"
 
 	"github.com/salty-max/lars/src/token"
 )
@@ -14,12 +14,24 @@ type Lexer struct {
 	ch           byte // current char under examination
 	col          int  // current column in line
 	line         int  // current line
+
+	comments []token.Token // comments skipped so far, kept as trivia
+	shebang  string        // leading #! line, without its newline
 }
 
-// New creates a new lexer.
+// New creates a new lexer. A leading shebang line (#!...) is skipped so
+// that scripts can be made executable; Shebang returns it.
 func New(input string) *Lexer {
 	l := &Lexer{input: input, line: 1}
 	l.readChar()
+
+	if strings.HasPrefix(input, "#!") {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		l.shebang = input[:l.position]
+	}
+
 	return l
 }
 
@@ -28,6 +40,10 @@ func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	for l.ch == '/' && l.peekChar() == '/' {
+		l.comments = append(l.comments, l.readComment())
+		l.skipWhitespace()
+	}
 
 	switch l.ch {
 	case '=':
@@ -118,7 +134,6 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peekChar() == '=' {
 			ch := l.ch
 			l.readChar()
-			fmt.Println(l.col)
 			literal := string(ch) + string(l.ch)
 			tok = token.Token{Type: token.NOT_EQ, Literal: literal, Col: l.col - 1, Line: l.line}
 		} else {
@@ -187,6 +202,17 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.BIT_XOR, l.ch, l.line, l.col)
 	case '~':
 		tok = newToken(token.BIT_NOT, l.ch, l.line, l.col)
+	case '"':
+		tok.Line = l.line
+		tok.Col = l.col
+		literal, ok := l.readString()
+		if ok {
+			tok.Type = token.STRING
+			tok.Literal = literal
+		} else {
+			tok.Type = token.ILLEGAL
+			tok.Literal = `"` + literal
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -219,6 +245,19 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// Comments returns the comments skipped so far, in source order. Comments
+// are trivia: they never reach the parser, but tools such as the formatter
+// use them to reproduce the source faithfully.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// Shebang returns the shebang line the input starts with, without its
+// newline, or "" if there is none.
+func (l *Lexer) Shebang() string {
+	return l.shebang
+}
+
 func newToken(tokenType token.TokenType, ch byte, line int, col int) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch), Col: col, Line: line}
 }
@@ -267,6 +306,41 @@ func (l *Lexer) readNumber() string {
 	return l.input[position:l.position]
 }
 
+// readString reads a string literal, leaving l.ch on the closing quote. It
+// returns the raw text between the quotes, escape sequences included, and
+// false if the input ends before the string is closed.
+func (l *Lexer) readString() (string, bool) {
+	position := l.position + 1
+	for {
+		l.readChar()
+		switch l.ch {
+		case '\\':
+			if l.peekChar() != 0 {
+				l.readChar()
+			}
+		case '"':
+			return l.input[position:l.position], true
+		case 0:
+			return l.input[position:l.position], false
+		case '\n':
+			l.line += 1
+			l.col = 0
+		}
+	}
+}
+
+func (l *Lexer) readComment() token.Token {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Col: l.col}
+
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	tok.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+
+	return tok
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.ch) {
@@ -160,3 +160,99 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestComments(t *testing.T) {
+	input := strings.Join([]string{
+		`// leading`,
+		`let x = 5; // trailing  `,
+		`x / 2 //last`,
+	}, "\n")
+
+	expectedTokens := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range expectedTokens {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	expectedComments := []token.Token{
+		{Type: token.COMMENT, Literal: "// leading", Line: 1, Col: 1},
+		{Type: token.COMMENT, Literal: "// trailing", Line: 2, Col: 12},
+		{Type: token.COMMENT, Literal: "//last", Line: 3, Col: 7},
+	}
+
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(expectedComments), len(comments))
+	}
+
+	for i, expected := range expectedComments {
+		if comments[i] != expected {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, expected, comments[i])
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	input := `"foobar" "foo bar" "say \"hi\"" "two
+lines" x "open`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		line            int
+		col             int
+	}{
+		{token.STRING, "foobar", 1, 1},
+		{token.STRING, "foo bar", 1, 10},
+		{token.STRING, `say \"hi\"`, 1, 20},
+		{token.STRING, "two\nlines", 1, 33},
+		{token.IDENT, "x", 2, 8},
+		{token.ILLEGAL, `"open`, 2, 10},
+		{token.EOF, "", 2, 16},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Line != tt.line || tok.Col != tt.col {
+			t.Fatalf("tests[%d] - wrong position. expected=%d:%d, got=%d:%d",
+				i, tt.line, tt.col, tok.Line, tok.Col)
+		}
+	}
+}
+
+func TestShebang(t *testing.T) {
+	l := New("#!/usr/bin/env lars\nlet x = 1;")
+
+	tok := l.NextToken()
+	if tok.Type != token.LET || tok.Line != 2 || tok.Col != 1 {
+		t.Fatalf("wrong first token. got=%q %q (%d:%d)", tok.Type, tok.Literal, tok.Line, tok.Col)
+	}
+	if l.Shebang() != "#!/usr/bin/env lars" {
+		t.Errorf("wrong shebang %q", l.Shebang())
+	}
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/salty-max/lars/src/term"
+)
+
+// ColorMode selects when output is colored.
+type ColorMode int
+
+const (
+	// ColorAuto colors output written to a terminal, unless the NO_COLOR
+	// environment variable is set.
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+func (m ColorMode) String() string {
+	return [...]string{"auto", "always", "never"}[m]
+}
+
+// ParseColorMode parses "auto", "always" or "never".
+func ParseColorMode(s string) (ColorMode, error) {
+	switch s {
+	case "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	}
+	return ColorAuto, fmt.Errorf("invalid color mode %q: want auto, always or never", s)
+}
+
+// Enabled reports whether output written to w should be colored.
+func (m ColorMode) Enabled(w io.Writer) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && IsTerminal(w)
+}
+
+// IsTerminal reports whether w writes to a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// Colors colors text, or leaves it plain when color is disabled.
+type Colors struct {
+	enabled bool
+}
+
+// NewColors returns the Colors to use for output written to w in mode.
+func NewColors(w io.Writer, mode ColorMode) Colors {
+	return Colors{enabled: mode.Enabled(w)}
+}
+
+// Enabled reports whether c colors text.
+func (c Colors) Enabled() bool { return c.enabled }
+
+// Colorize wraps text in color if c is enabled.
+func (c Colors) Colorize(color ANSIColor, text string) string {
+	if !c.enabled {
+		return text
+	}
+	return Colorize(color, text)
+}
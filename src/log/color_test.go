@@ -0,0 +1,64 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseColorMode(t *testing.T) {
+	for _, mode := range []ColorMode{ColorAuto, ColorAlways, ColorNever} {
+		parsed, err := ParseColorMode(mode.String())
+		if err != nil || parsed != mode {
+			t.Errorf("ParseColorMode(%q) = %v, %v", mode.String(), parsed, err)
+		}
+	}
+
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Errorf("expected an error for an invalid mode")
+	}
+}
+
+func TestColorModeEnabled(t *testing.T) {
+	var buf bytes.Buffer
+
+	tests := []struct {
+		mode     ColorMode
+		noColor  string
+		expected bool
+	}{
+		{ColorAuto, "", false}, // a buffer is not a terminal
+		{ColorAlways, "", true},
+		{ColorAlways, "1", true},
+		{ColorNever, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("NO_COLOR", tt.noColor)
+		if got := tt.mode.Enabled(&buf); got != tt.expected {
+			t.Errorf("%s with NO_COLOR=%q: want=%t, got=%t", tt.mode, tt.noColor, tt.expected, got)
+		}
+	}
+}
+
+func TestColors(t *testing.T) {
+	var buf bytes.Buffer
+
+	if got := NewColors(&buf, ColorNever).Colorize(RED, "oops"); got != "oops" {
+		t.Errorf("disabled colors changed the text: %q", got)
+	}
+	if got := NewColors(&buf, ColorAlways).Colorize(RED, "oops"); got != "\033[31moops\033[0m" {
+		t.Errorf("enabled colors did not color the text: %q", got)
+	}
+}
+
+func TestLoggerPlainOutput(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, WithTimestamps(false))
+
+	logger.Info("hello")
+	logger.Error("oops")
+
+	if buf.String() != "hello\nERROR oops\n" {
+		t.Errorf("wrong output %q", buf.String())
+	}
+}
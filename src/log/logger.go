@@ -1,10 +1,14 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 type ANSIColor string
@@ -29,45 +33,250 @@ const (
 type LogLevel int
 
 const (
-	INFO LogLevel = iota
+	TRACE LogLevel = iota
+	DEBUG
+	INFO
 	WARN
 	ERROR
 )
 
 func (l LogLevel) String() string {
-	return [...]string{"INFO", "WARN", "ERROR"}[l]
+	return [...]string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}[l]
+}
+
+func (l LogLevel) color() ANSIColor {
+	return [...]ANSIColor{MAGENTA, CYAN, BLUE, YELLOW, RED}[l]
+}
+
+// ParseLevel parses a level name such as "debug", in any case.
+func ParseLevel(s string) (LogLevel, error) {
+	for l := TRACE; l <= ERROR; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return INFO, fmt.Errorf("invalid log level %q", s)
+}
+
+// Format is the layout of log lines.
+type Format int
+
+const (
+	// TextFormat writes the message followed by key=value fields. Levels
+	// other than INFO are named in front of the message.
+	TextFormat Format = iota
+	// JSONFormat writes one JSON object per line.
+	JSONFormat
+)
+
+// Field is a key/value pair attached to a log line.
+type Field struct {
+	Key   string
+	Value any
 }
 
 type Logger struct {
-	mu     sync.Mutex
-	output *log.Logger
+	mu     *sync.Mutex // shared with the loggers made by With
+	out    io.Writer
+	colors Colors
+
+	level      LogLevel
+	format     Format
+	timestamps bool
+	fields     []Field
+}
+
+// Option configures a Logger.
+type Option func(*config)
+
+type config struct {
+	color      ColorMode
+	timestamps bool
+	level      LogLevel
+	format     Format
 }
 
-func NewLogger(out io.Writer) *Logger {
+// WithColor sets when the logger colors its output. The default is
+// ColorAuto. JSON output is never colored.
+func WithColor(mode ColorMode) Option {
+	return func(c *config) { c.color = mode }
+}
+
+// WithTimestamps sets whether lines are prefixed with the date and time.
+// They are by default.
+func WithTimestamps(on bool) Option {
+	return func(c *config) { c.timestamps = on }
+}
+
+// WithLevel sets the minimum level logged. The default is INFO.
+func WithLevel(level LogLevel) Option {
+	return func(c *config) { c.level = level }
+}
+
+// WithFormat sets the output format. The default is TextFormat.
+func WithFormat(format Format) Option {
+	return func(c *config) { c.format = format }
+}
+
+func NewLogger(out io.Writer, opts ...Option) *Logger {
+	cfg := config{color: ColorAuto, timestamps: true, level: INFO, format: TextFormat}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	colors := NewColors(out, cfg.color)
+	if cfg.format == JSONFormat {
+		colors = Colors{}
+	}
+
 	return &Logger{
-		output: log.New(out, "", log.LstdFlags),
+		mu:         &sync.Mutex{},
+		out:        out,
+		colors:     colors,
+		level:      cfg.level,
+		format:     cfg.format,
+		timestamps: cfg.timestamps,
+	}
+}
+
+// With returns a logger that adds the key/value pairs in args to every
+// line. Keys are strings, each followed by its value.
+func (l *Logger) With(args ...any) *Logger {
+	child := *l
+	child.fields = append(l.fields[:len(l.fields):len(l.fields)], fields(args)...)
+	return &child
+}
+
+// Enabled reports whether lines at level are logged.
+func (l *Logger) Enabled(level LogLevel) bool {
+	return level >= l.level
+}
+
+// Log writes msg at level, with the key/value pairs in args.
+func (l *Logger) Log(level LogLevel, msg string, args ...any) {
+	if l.Enabled(level) {
+		l.write(level, time.Now(), msg, fields(args))
 	}
 }
 
-func (l *Logger) log(color ANSIColor, text string) {
+func (l *Logger) Trace(msg string, args ...any) { l.Log(TRACE, msg, args...) }
+func (l *Logger) Debug(msg string, args ...any) { l.Log(DEBUG, msg, args...) }
+func (l *Logger) Info(msg string, args ...any)  { l.Log(INFO, msg, args...) }
+func (l *Logger) Warn(msg string, args ...any)  { l.Log(WARN, msg, args...) }
+func (l *Logger) Error(msg string, args ...any) { l.Log(ERROR, msg, args...) }
+
+func (l *Logger) write(level LogLevel, t time.Time, msg string, extra []Field) {
+	all := append(l.fields[:len(l.fields):len(l.fields)], extra...)
+
+	var line []byte
+	if l.format == JSONFormat {
+		line = l.jsonLine(level, t, msg, all)
+	} else {
+		line = l.textLine(level, t, msg, all)
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	l.output.Println(Colorize(color, text))
+	l.out.Write(line)
+}
+
+func (l *Logger) textLine(level LogLevel, t time.Time, msg string, fields []Field) []byte {
+	var buf bytes.Buffer
+
+	if l.timestamps {
+		buf.WriteString(t.Format("2006/01/02 15:04:05 "))
+	}
+	if level != INFO {
+		msg = level.String() + " " + msg
+	}
+	buf.WriteString(l.colors.Colorize(level.color(), msg))
+
+	for _, f := range fields {
+		buf.WriteString(" " + f.Key + "=")
+		buf.WriteString(quoteIfNeeded(fmt.Sprint(plain(f.Value))))
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes()
 }
 
-func (l *Logger) Info(text string) {
-	l.log(BLUE, text)
+func (l *Logger) jsonLine(level LogLevel, t time.Time, msg string, fields []Field) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	if l.timestamps {
+		writeJSONField(&buf, "time", t.Format(time.RFC3339Nano))
+		buf.WriteByte(',')
+	}
+	writeJSONField(&buf, "level", level.String())
+	buf.WriteByte(',')
+	writeJSONField(&buf, "msg", msg)
+	for _, f := range fields {
+		buf.WriteByte(',')
+		writeJSONField(&buf, f.Key, plain(f.Value))
+	}
+	buf.WriteString("}\n")
+
+	return buf.Bytes()
 }
 
-func (l *Logger) Warn(text string) {
-	l.log(YELLOW, text)
+func writeJSONField(buf *bytes.Buffer, key string, value any) {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(v)
 }
 
-func (l *Logger) Error(text string) {
-	l.log(RED, text)
+// plain turns errors and Stringers into their text, which is how they are
+// meant to be read.
+func plain(value any) any {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// fields pairs up keys and values. A value without a key is logged under
+// "!BADKEY", as log/slog does.
+func fields(args []any) []Field {
+	var fs []Field
+	for len(args) > 0 {
+		if f, ok := args[0].(Field); ok {
+			fs = append(fs, f)
+			args = args[1:]
+			continue
+		}
+
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			fs = append(fs, Field{Key: "!BADKEY", Value: args[0]})
+			args = args[1:]
+			continue
+		}
+
+		fs = append(fs, Field{Key: key, Value: args[1]})
+		args = args[2:]
+	}
+	return fs
 }
 
+// Colorize wraps text in color, whatever the output. Use Colors to respect
+// the color mode.
 func Colorize(color ANSIColor, text string) string {
 	return fmt.Sprintf("%s%s%s", color, text, RESET)
 }
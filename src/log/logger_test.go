@@ -0,0 +1,111 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func newTestLogger(buf *bytes.Buffer, opts ...Option) *Logger {
+	opts = append([]Option{WithTimestamps(false), WithColor(ColorNever)}, opts...)
+	return NewLogger(buf, opts...)
+}
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf, WithLevel(DEBUG))
+
+	logger.Trace("hidden")
+	logger.Debug("step")
+	logger.Info("hello")
+	logger.Warn("careful")
+	logger.Error("oops")
+
+	expected := "DEBUG step\nhello\nWARN careful\nERROR oops\n"
+	if buf.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, s := range []string{"trace", "DEBUG", "Info", "warn", "error"} {
+		level, err := ParseLevel(s)
+		if err != nil || !strings.EqualFold(level.String(), s) {
+			t.Errorf("ParseLevel(%q) = %v, %v", s, level, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Errorf("expected an error for an invalid level")
+	}
+}
+
+func TestTextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf).With("file", "main.lars")
+
+	logger.Info("evaluated", "line", 3, "result", "two words", "err", errors.New("boom"), "dangling")
+
+	expected := `evaluated file=main.lars line=3 result="two words" err=boom !BADKEY=dangling` + "\n"
+	if buf.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot= %q", expected, buf.String())
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, WithFormat(JSONFormat), WithColor(ColorAlways))
+
+	logger.With("session", 7).Warn("slow", "ms", 250, "ok", false)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("output is not JSON: %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]any{"level": "WARN", "msg": "slow", "session": 7.0, "ms": 250.0, "ok": false}
+	for key, value := range expected {
+		if line[key] != value {
+			t.Errorf("wrong %s. want=%v, got=%v", key, value, line[key])
+		}
+	}
+	if _, ok := line["time"]; !ok {
+		t.Errorf("missing time in %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "\x1b") {
+		t.Errorf("JSON output should not be colored: %q", buf.String())
+	}
+}
+
+func TestWithDoesNotShareFields(t *testing.T) {
+	var buf bytes.Buffer
+	base := newTestLogger(&buf).With("a", 1)
+
+	base.With("b", 2).Info("first")
+	base.With("c", 3).Info("second")
+
+	expected := "first a=1 b=2\nsecond a=1 c=3\n"
+	if buf.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, buf.String())
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newTestLogger(&buf, WithLevel(DEBUG)).Handler())
+
+	logger.Debug("parsed", "statements", 4)
+	logger.Log(context.Background(), slog.LevelDebug-4, "hidden")
+	logger.With("host", "api").WithGroup("req").Info("call", "fn", "add", slog.Group("args", "n", 2))
+	logger.Error("failed", slog.Any("err", errors.New("boom")))
+
+	expected := "DEBUG parsed statements=4\n" +
+		"call host=api req.fn=add req.args.n=2\n" +
+		"ERROR failed err=boom\n"
+	if buf.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot= %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Handler returns a log/slog handler that writes through l, so that code
+// written against slog logs with the same level, format and output.
+func (l *Logger) Handler() slog.Handler {
+	return &handler{logger: l}
+}
+
+type handler struct {
+	logger *Logger
+	group  string // prefix of attribute keys, ending in a dot
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(levelFromSlog(level))
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	var fs []Field
+	r.Attrs(func(a slog.Attr) bool {
+		fs = appendAttr(fs, h.group, a)
+		return true
+	})
+
+	h.logger.write(levelFromSlog(r.Level), r.Time, r.Message, fs)
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fs []Field
+	for _, a := range attrs {
+		fs = appendAttr(fs, h.group, a)
+	}
+
+	logger := *h.logger
+	logger.fields = append(logger.fields[:len(logger.fields):len(logger.fields)], fs...)
+	return &handler{logger: &logger, group: h.group}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &handler{logger: h.logger, group: h.group + name + "."}
+}
+
+// appendAttr appends a as fields, flattening groups into dotted keys.
+func appendAttr(fs []Field, prefix string, a slog.Attr) []Field {
+	value := a.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, attr := range value.Group() {
+			fs = appendAttr(fs, prefix, attr)
+		}
+		return fs
+	}
+
+	if a.Equal(slog.Attr{}) {
+		return fs
+	}
+	return append(fs, Field{Key: prefix + a.Key, Value: value.Any()})
+}
+
+// levelFromSlog maps slog levels to the nearest LogLevel at or below them.
+func levelFromSlog(level slog.Level) LogLevel {
+	switch {
+	case level >= slog.LevelError:
+		return ERROR
+	case level >= slog.LevelWarn:
+		return WARN
+	case level >= slog.LevelInfo:
+		return INFO
+	case level >= slog.LevelDebug:
+		return DEBUG
+	}
+	return TRACE
+}
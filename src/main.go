@@ -2,16 +2,10 @@ package main
 
 import (
 	"os"
-	"os/user"
 
-	"github.com/salty-max/lars/src/repl"
+	"github.com/salty-max/lars/src/cli"
 )
 
 func main() {
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
-	}
-
-	repl.Start(os.Stdin, os.Stdout, user)
+	os.Exit(cli.Main(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,152 @@
+package object
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Capabilities that builtins with side effects need.
+const (
+	CapFSRead = "fs.read" // reading files; scoped by directory
+	CapEnv    = "env"     // reading environment variables; scoped by name
+	CapClock  = "clock"   // reading the time
+	CapRandom = "random"  // drawing random numbers
+	CapStdout = "stdout"  // writing to standard output
+	CapStderr = "stderr"  // writing to standard error
+	CapStdin  = "stdin"   // reading standard input
+)
+
+var knownCapabilities = map[string]bool{
+	CapFSRead: true,
+	CapEnv:    true,
+	CapClock:  true,
+	CapRandom: true,
+	CapStdout: true,
+	CapStderr: true,
+	CapStdin:  true,
+}
+
+// Capability grants scripts one kind of side effect. A capability with a
+// scope only covers the resources in that scope: the files under a
+// directory for fs.read, or a single variable for env.
+type Capability struct {
+	Name  string
+	Scope string // empty for the whole capability
+}
+
+// ParseCapability parses a grant written as name or name:scope, such as
+// "clock" or "fs.read:/data".
+func ParseCapability(s string) (Capability, error) {
+	name, scope, _ := strings.Cut(s, ":")
+	if !knownCapabilities[name] {
+		return Capability{}, fmt.Errorf("unknown capability %q", name)
+	}
+	return Capability{Name: name, Scope: scope}, nil
+}
+
+func (c Capability) String() string {
+	if c.Scope == "" {
+		return c.Name
+	}
+	return c.Name + ":" + c.Scope
+}
+
+// covers reports whether c allows the capability name on resource.
+func (c Capability) covers(name, resource string) bool {
+	if c.Name != name {
+		return false
+	}
+	if c.Scope == "" {
+		return true
+	}
+
+	if name == CapFSRead {
+		rel, err := filepath.Rel(realPath(c.Scope), realPath(resource))
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	return c.Scope == resource
+}
+
+// realPath returns the absolute path of path with symbolic links resolved,
+// so that a link cannot lead out of a granted directory. When path does not
+// exist, the links in its longest existing prefix are still resolved.
+func realPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+
+	dir, base := filepath.Split(filepath.Clean(path))
+	if dir == "" || filepath.Clean(dir) == path {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(realPath(dir), base)
+}
+
+// Grants is the set of capabilities granted to the code running in an
+// environment. An environment without grants is not sandboxed; empty
+// grants allow no side effects at all.
+type Grants struct {
+	caps []Capability
+}
+
+// NewGrants grants caps.
+func NewGrants(caps ...Capability) *Grants {
+	return &Grants{caps: caps}
+}
+
+// ParseGrants grants the capabilities written in specs, as parsed by
+// ParseCapability.
+func ParseGrants(specs ...string) (*Grants, error) {
+	g := &Grants{}
+	for _, spec := range specs {
+		c, err := ParseCapability(spec)
+		if err != nil {
+			return nil, err
+		}
+		g.caps = append(g.caps, c)
+	}
+	return g, nil
+}
+
+// Allows reports whether the capability name is granted for resource,
+// which is empty for capabilities without a scope. Nil grants allow
+// everything.
+func (g *Grants) Allows(name, resource string) bool {
+	if g == nil {
+		return true
+	}
+	for _, c := range g.caps {
+		if c.covers(name, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns the capabilities granted.
+func (g *Grants) Capabilities() []Capability {
+	if g == nil {
+		return nil
+	}
+	return append([]Capability(nil), g.caps...)
+}
+
+// PermissionError is the Err of the error raised when a builtin is called
+// without the capability it needs. It matches fs.ErrPermission.
+type PermissionError struct {
+	Builtin    string
+	Capability Capability // the capability needed, scoped to the resource
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("permission denied: `%s` needs the %s capability", e.Builtin, e.Capability)
+}
+
+func (e *PermissionError) Is(target error) bool {
+	return target == fs.ErrPermission
+}
@@ -0,0 +1,94 @@
+package object
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCapability(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Capability
+		err      string
+	}{
+		{"clock", Capability{Name: CapClock}, ""},
+		{"fs.read:/data", Capability{Name: CapFSRead, Scope: "/data"}, ""},
+		{"env:HOME", Capability{Name: CapEnv, Scope: "HOME"}, ""},
+		{"net", Capability{}, `unknown capability "net"`},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCapability(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("ParseCapability(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || c != tt.expected {
+			t.Errorf("ParseCapability(%q) = %+v, %v; want %+v", tt.input, c, err, tt.expected)
+		}
+		if c.String() != tt.input {
+			t.Errorf("String() = %q, want %q", c.String(), tt.input)
+		}
+	}
+}
+
+func TestGrantsAllows(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	if err := os.Mkdir(data, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dir, filepath.Join(data, "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	grants, err := ParseGrants("fs.read:"+data, "env:HOME", "clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, resource string
+		expected       bool
+	}{
+		{CapFSRead, filepath.Join(data, "rules.lars"), true},
+		{CapFSRead, filepath.Join(data, "sub", "x"), true},
+		{CapFSRead, data, true},
+		{CapFSRead, filepath.Join(data, "..", "secret"), false},
+		{CapFSRead, filepath.Join(data, "up", "secret"), false},
+		{CapFSRead, data + "-other/x", false},
+		{CapEnv, "HOME", true},
+		{CapEnv, "PATH", false},
+		{CapClock, "", true},
+		{CapRandom, "", false},
+	}
+
+	for _, tt := range tests {
+		if got := grants.Allows(tt.name, tt.resource); got != tt.expected {
+			t.Errorf("Allows(%s, %q) = %t, want %t", tt.name, tt.resource, got, tt.expected)
+		}
+	}
+
+	var none *Grants
+	if !none.Allows(CapRandom, "") {
+		t.Errorf("nil grants should allow everything")
+	}
+	if NewGrants().Allows(CapClock, "") {
+		t.Errorf("empty grants should allow nothing")
+	}
+}
+
+func TestPermissionError(t *testing.T) {
+	err := &PermissionError{Builtin: "now", Capability: Capability{Name: CapClock}}
+	if want := "permission denied: `now` needs the clock capability"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("PermissionError does not match fs.ErrPermission")
+	}
+}
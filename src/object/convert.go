@@ -0,0 +1,484 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// ConversionError reports a value that FromGo or ToGo cannot convert.
+type ConversionError struct {
+	// Path locates the value inside the one converted, such as [2] for an
+	// array element, ["id"] for a hash value or .Name for a struct field.
+	// It is empty for the value itself.
+	Path string
+	// Msg describes the problem, phrased to follow the value it is about:
+	// "must be INTEGER, got STRING".
+	Msg string
+}
+
+func (e *ConversionError) Error() string {
+	subject := "value"
+	if e.Path != "" {
+		subject += " at " + e.Path
+	}
+	return "object: " + subject + " " + e.Msg
+}
+
+// FromGo converts a Go value to a lars value:
+//
+//   - nil, and nil pointers, slices and maps, become NULL;
+//   - booleans, integers, floats and strings become their lars types;
+//   - time.Time becomes a STRING in RFC 3339 format;
+//   - slices and arrays become ARRAYs;
+//   - maps with string, integer or boolean keys become HASHes, with keys
+//     in sorted order;
+//   - structs become HASHes of their exported fields, in declaration order;
+//   - pointers and interfaces are followed;
+//   - Objects are returned as is.
+//
+// Struct fields are named by a `lars:"name"` tag if they have one; a tag of
+// "-" skips the field, while "-," names it "-", and the "omitempty" option
+// skips zero values. Values that contain themselves, through pointers, maps
+// or slices, cannot be converted.
+func FromGo(v any) (Object, error) {
+	return fromValue(reflect.ValueOf(v), "", map[visit]bool{})
+}
+
+// ToGo converts obj into the Go value target points to. It is the inverse
+// of FromGo: HASHes decode into maps and structs, ARRAYs into slices and
+// arrays, NULL into nil, and anything into an interface{}, using int64,
+// float64, bool, string, []any and map[string]any (or map[any]any when the
+// keys are not all strings). Iterable host objects decode into slices.
+// Keys of a HASH with no matching struct field are ignored. Integers
+// decode into floats, and strings and integers (seconds since the Unix
+// epoch) into time.Time.
+func ToGo(obj Object, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("object: ToGo target must be a non-nil pointer, got %T", target)
+	}
+	return toValue(obj, v.Elem(), "")
+}
+
+// visit identifies a pointer, map or slice that FromGo is converting, to
+// detect values that contain themselves. Slices are told apart by length,
+// as a slice may share its start with a longer one.
+type visit struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+func fromValue(v reflect.Value, path string, visiting map[visit]bool) (Object, error) {
+	if !v.IsValid() {
+		return NULL, nil
+	}
+	if v.CanInterface() {
+		if obj, ok := v.Interface().(Object); ok {
+			if v.Kind() == reflect.Pointer && v.IsNil() {
+				return NULL, nil
+			}
+			return obj, nil
+		}
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		return &String{Value: t.Format(time.RFC3339Nano)}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			break
+		}
+		key := visit{typ: v.Type(), ptr: v.Pointer()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if visiting[key] {
+			return nil, &ConversionError{Path: path, Msg: fmt.Sprintf("forms a cycle via %s", v.Type())}
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return NativeBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, &ConversionError{Path: path, Msg: fmt.Sprintf("overflows INTEGER: %d", v.Uint())}
+		}
+		return &Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return fromValue(v.Elem(), path, visiting)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return NULL, nil
+		}
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			el, err := fromValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return fromMap(v, path, visiting)
+	case reflect.Struct:
+		return fromStruct(v, path, visiting)
+	}
+
+	return nil, &ConversionError{Path: path, Msg: fmt.Sprintf("cannot be converted from %s", v.Type())}
+}
+
+func fromMap(v reflect.Value, path string, visiting map[visit]bool) (Object, error) {
+	type entry struct {
+		key   Hashable
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k, err := fromValue(iter.Key(), path, visiting)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(Hashable)
+		if !ok {
+			return nil, &ConversionError{Path: path, Msg: fmt.Sprintf("has a key unusable as hash key: %s", k.Type())}
+		}
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return lessKey(entries[i].key, entries[j].key)
+	})
+
+	hash := NewHash()
+	for _, e := range entries {
+		value, err := fromValue(e.value, path+"["+quoteKey(e.key)+"]", visiting)
+		if err != nil {
+			return nil, err
+		}
+		hash.Set(e.key, value)
+	}
+	return hash, nil
+}
+
+// lessKey orders hash keys of the same type by value, and keys of
+// different types by type name.
+func lessKey(a, b Hashable) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value < b.(*Integer).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	}
+	return a.Inspect() < b.Inspect()
+}
+
+func fromStruct(v reflect.Value, path string, visiting map[visit]bool) (Object, error) {
+	hash := NewHash()
+	for _, f := range structFields(v.Type()) {
+		fv := v.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		value, err := fromValue(fv, path+"."+f.goName, visiting)
+		if err != nil {
+			return nil, err
+		}
+		hash.Set(&String{Value: f.name}, value)
+	}
+	return hash, nil
+}
+
+type field struct {
+	index     int
+	goName    string
+	name      string // name in lars
+	omitEmpty bool
+}
+
+// structFields lists the exported fields of t that lars sees.
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("lars")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{
+			index:     i,
+			goName:    sf.Name,
+			name:      name,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+	return fields
+}
+
+func toValue(obj Object, v reflect.Value, path string) error {
+	t := v.Type()
+
+	mismatch := func(want ObjectType) error {
+		return &ConversionError{Path: path, Msg: fmt.Sprintf("must be %s, got %s", want, obj.Type())}
+	}
+
+	if t == objectType {
+		v.Set(reflect.ValueOf(&obj).Elem())
+		return nil
+	}
+	if t == timeType {
+		return toTime(obj, v, path)
+	}
+
+	// NULL decodes into the zero value of the types that can be nil
+	if _, ok := obj.(*Null); ok {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch(INTEGER_OBJ)
+		}
+		if v.OverflowInt(i.Value) {
+			return &ConversionError{Path: path, Msg: fmt.Sprintf("overflows %s: %d", t, i.Value)}
+		}
+		v.SetInt(i.Value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch(INTEGER_OBJ)
+		}
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return &ConversionError{Path: path, Msg: fmt.Sprintf("overflows %s: %d", t, i.Value)}
+		}
+		v.SetUint(uint64(i.Value))
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case *Float:
+			v.SetFloat(n.Value)
+		case *Integer:
+			v.SetFloat(float64(n.Value))
+		default:
+			return mismatch(FLOAT_OBJ)
+		}
+	case reflect.Bool:
+		b, ok := obj.(*Boolean)
+		if !ok {
+			return mismatch(BOOLEAN_OBJ)
+		}
+		v.SetBool(b.Value)
+	case reflect.String:
+		s, ok := obj.(*String)
+		if !ok {
+			return mismatch(STRING_OBJ)
+		}
+		v.SetString(s.Value)
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return toValue(obj, v.Elem(), path)
+	case reflect.Slice:
+		a, ok := obj.(*Array)
+		if it, iterable := obj.(Iterable); !ok && iterable {
+			var err error
+			if a, err = collect(it); err != nil {
+				return &ConversionError{Path: path, Msg: "failed to iterate: " + err.Error()}
+			}
+		} else if !ok {
+			return mismatch(ARRAY_OBJ)
+		}
+		v.Set(reflect.MakeSlice(t, len(a.Elements), len(a.Elements)))
+		return toElements(a, v, path)
+	case reflect.Array:
+		a, ok := obj.(*Array)
+		if !ok {
+			return mismatch(ARRAY_OBJ)
+		}
+		if len(a.Elements) != t.Len() {
+			return &ConversionError{Path: path, Msg: fmt.Sprintf("must have %d elements, got %d", t.Len(), len(a.Elements))}
+		}
+		return toElements(a, v, path)
+	case reflect.Map:
+		h, ok := obj.(*Hash)
+		if !ok {
+			return mismatch(HASH_OBJ)
+		}
+		v.Set(reflect.MakeMapWithSize(t, h.Len()))
+		for _, pair := range h.Pairs() {
+			key := reflect.New(t.Key()).Elem()
+			if err := toValue(pair.Key, key, path); err != nil {
+				return &ConversionError{Path: path, Msg: "key " + err.(*ConversionError).Msg}
+			}
+			value := reflect.New(t.Elem()).Elem()
+			if err := toValue(pair.Value, value, path+"["+quoteKey(pair.Key)+"]"); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Struct:
+		h, ok := obj.(*Hash)
+		if !ok {
+			return mismatch(HASH_OBJ)
+		}
+		for _, f := range structFields(t) {
+			value, ok := h.Get(&String{Value: f.name})
+			if !ok {
+				continue
+			}
+			if err := toValue(value, v.Field(f.index), path+"."+f.goName); err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return &ConversionError{Path: path, Msg: fmt.Sprintf("cannot be converted to %s", t)}
+		}
+		if natural := toNatural(obj); natural != nil {
+			v.Set(reflect.ValueOf(natural))
+		} else {
+			v.Set(reflect.Zero(t))
+		}
+	default:
+		return &ConversionError{Path: path, Msg: fmt.Sprintf("cannot be converted to %s", t)}
+	}
+
+	return nil
+}
+
+func toElements(a *Array, v reflect.Value, path string) error {
+	for i, el := range a.Elements {
+		if err := toValue(el, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collect gathers the values of it into an array.
+func collect(it Iterable) (*Array, error) {
+	a := &Array{}
+	err := it.Iterate(func(el Object) bool {
+		a.Elements = append(a.Elements, el)
+		return true
+	})
+	return a, err
+}
+
+func toTime(obj Object, v reflect.Value, path string) error {
+	switch obj := obj.(type) {
+	case *String:
+		t, err := time.Parse(time.RFC3339Nano, obj.Value)
+		if err != nil {
+			return &ConversionError{Path: path, Msg: fmt.Sprintf("is not an RFC 3339 time: %q", obj.Value)}
+		}
+		v.Set(reflect.ValueOf(t))
+	case *Integer:
+		v.Set(reflect.ValueOf(time.Unix(obj.Value, 0).UTC()))
+	default:
+		return &ConversionError{Path: path, Msg: fmt.Sprintf("must be STRING or INTEGER, got %s", obj.Type())}
+	}
+	return nil
+}
+
+// toNatural converts obj to the Go type that fits it best, for targets of
+// type any. Functions and other values without a Go counterpart are passed
+// as is.
+func toNatural(obj Object) any {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value
+	case *Float:
+		return obj.Value
+	case *Boolean:
+		return obj.Value
+	case *String:
+		return obj.Value
+	case *Null:
+		return nil
+	case *Array:
+		s := make([]any, len(obj.Elements))
+		for i, el := range obj.Elements {
+			s[i] = toNatural(el)
+		}
+		return s
+	case *Hash:
+		stringKeys := true
+		for _, pair := range obj.Pairs() {
+			if _, ok := pair.Key.(*String); !ok {
+				stringKeys = false
+				break
+			}
+		}
+
+		if stringKeys {
+			m := make(map[string]any, obj.Len())
+			for _, pair := range obj.Pairs() {
+				m[pair.Key.(*String).Value] = toNatural(pair.Value)
+			}
+			return m
+		}
+
+		m := make(map[any]any, obj.Len())
+		for _, pair := range obj.Pairs() {
+			m[toNatural(pair.Key)] = toNatural(pair.Value)
+		}
+		return m
+	}
+	return obj
+}
+
+func quoteKey(key Object) string {
+	if s, ok := key.(*String); ok {
+		return fmt.Sprintf("%q", s.Value)
+	}
+	return key.Inspect()
+}
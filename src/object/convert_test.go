@@ -0,0 +1,242 @@
+package object
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `lars:"city"`
+	Zip  string `lars:"zip,omitempty"`
+}
+
+type person struct {
+	Name    string   `lars:"name"`
+	Age     int      `lars:"age"`
+	Tags    []string `lars:"tags"`
+	Address *address `lars:"address"`
+	Secret  string   `lars:"-"`
+	Dash    string   `lars:"-,omitempty"`
+	Plain   bool
+	hidden  int
+}
+
+func TestFromGo(t *testing.T) {
+	when := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	var nilPtr *person
+	var nilSlice []int
+	var nilMap map[string]int
+
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{nil, "null"},
+		{nilPtr, "null"},
+		{nilSlice, "null"},
+		{nilMap, "null"},
+		{true, "true"},
+		{int8(-3), "-3"},
+		{uint16(7), "7"},
+		{2.5, "2.500000"},
+		{"hi", "hi"},
+		{when, "2024-03-01T12:30:00Z"},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]bool{true, false}, "[true, false]"},
+		{map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{map[int]string{10: "x", 2: "y"}, "{2: y, 10: x}"},
+		{&Integer{Value: 4}, "4"},
+		{
+			person{Name: "Ada", Age: 36, Tags: []string{"math"}, Address: &address{City: "London"}, Secret: "s", Dash: "d", Plain: true},
+			"{name: Ada, age: 36, tags: [math], address: {city: London}, -: d, Plain: true}",
+		},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt.value)
+		if err != nil {
+			t.Errorf("FromGo(%#v) failed: %v", tt.value, err)
+			continue
+		}
+		if got := obj.Inspect(); got != tt.expected {
+			t.Errorf("FromGo(%#v) = %s, want %s", tt.value, got, tt.expected)
+		}
+	}
+}
+
+// node is a linked list, for values that refer back to themselves.
+type node struct{ Next *node }
+
+func TestFromGoErrors(t *testing.T) {
+	loop := &node{}
+	loop.Next = loop
+	self := map[string]any{}
+	self["self"] = self
+	nested := []any{nil}
+	nested[0] = nested
+
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{make(chan int), "object: value cannot be converted from chan int"},
+		{[]any{1, func() {}}, "object: value at [1] cannot be converted from func()"},
+		{map[string]any{"n": uint64(1 << 63)}, "object: value at [\"n\"] overflows INTEGER: 9223372036854775808"},
+		{struct{ F []any }{[]any{complex(1, 2)}}, "object: value at .F[0] cannot be converted from complex128"},
+		{map[float64]int{1.5: 1}, "object: value has a key unusable as hash key: FLOAT"},
+		{loop, "object: value at .Next forms a cycle via *object.node"},
+		{self, "object: value at [\"self\"] forms a cycle via map[string]interface {}"},
+		{nested, "object: value at [0] forms a cycle via []interface {}"},
+	}
+
+	for _, tt := range tests {
+		_, err := FromGo(tt.value)
+		if err == nil {
+			t.Errorf("FromGo(%#v) succeeded, want error", tt.value)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("FromGo(%#v) error = %q, want %q", tt.value, err, tt.expected)
+		}
+	}
+}
+
+func TestFromGoSharedValues(t *testing.T) {
+	// a value reached twice without a cycle is converted twice
+	shared := &address{City: "Paris"}
+	obj, err := FromGo([]*address{shared, shared})
+	if err != nil {
+		t.Fatalf("FromGo failed: %v", err)
+	}
+	if want := "[{city: Paris}, {city: Paris}]"; obj.Inspect() != want {
+		t.Errorf("wrong result. want=%s, got=%s", want, obj.Inspect())
+	}
+}
+
+func TestToGo(t *testing.T) {
+	src := person{Name: "Ada", Age: 36, Tags: []string{"math", "poetry"}, Address: &address{City: "London", Zip: "N1"}, Plain: true}
+	obj, err := FromGo(src)
+	if err != nil {
+		t.Fatalf("FromGo failed: %v", err)
+	}
+
+	var decoded person
+	if err := ToGo(obj, &decoded); err != nil {
+		t.Fatalf("ToGo failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, src) {
+		t.Errorf("round trip = %+v, want %+v", decoded, src)
+	}
+
+	var natural any
+	if err := ToGo(obj, &natural); err != nil {
+		t.Fatalf("ToGo into any failed: %v", err)
+	}
+	want := map[string]any{
+		"name":    "Ada",
+		"age":     int64(36),
+		"tags":    []any{"math", "poetry"},
+		"address": map[string]any{"city": "London", "zip": "N1"},
+		"Plain":   true,
+	}
+	if !reflect.DeepEqual(natural, want) {
+		t.Errorf("ToGo into any = %#v, want %#v", natural, want)
+	}
+
+	mixed := NewHash()
+	mixed.Set(&Integer{Value: 1}, &String{Value: "one"})
+	mixed.Set(TRUE, NULL)
+	if err := ToGo(mixed, &natural); err != nil {
+		t.Fatalf("ToGo into any failed: %v", err)
+	}
+	if want := map[any]any{int64(1): "one", true: nil}; !reflect.DeepEqual(natural, want) {
+		t.Errorf("ToGo into any = %#v, want %#v", natural, want)
+	}
+
+	var when time.Time
+	if err := ToGo(&String{Value: "2024-03-01T12:30:00Z"}, &when); err != nil {
+		t.Fatalf("ToGo into time.Time failed: %v", err)
+	}
+	if want := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC); !when.Equal(want) {
+		t.Errorf("ToGo into time.Time = %v, want %v", when, want)
+	}
+	if err := ToGo(&Integer{Value: 0}, &when); err != nil || !when.Equal(time.Unix(0, 0)) {
+		t.Errorf("ToGo(0) into time.Time = %v, %v", when, err)
+	}
+
+	ptr := &address{}
+	if err := ToGo(NULL, &ptr); err != nil || ptr != nil {
+		t.Errorf("ToGo(null) into pointer = %v, %v", ptr, err)
+	}
+
+	var f float64
+	if err := ToGo(&Integer{Value: 3}, &f); err != nil || f != 3 {
+		t.Errorf("ToGo(3) into float64 = %v, %v", f, err)
+	}
+
+	var o Object
+	if err := ToGo(obj, &o); err != nil || o != obj {
+		t.Errorf("ToGo into Object = %v, %v", o, err)
+	}
+}
+
+func TestToGoErrors(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&String{Value: "age"}, &String{Value: "old"})
+	list := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 300}}}
+
+	var p person
+	var small []int8
+	var pair [3]int
+	var counts map[string]int
+	var n int
+
+	tests := []struct {
+		obj      Object
+		target   any
+		expected string
+	}{
+		{hash, &p, "object: value at .Age must be INTEGER, got STRING"},
+		{list, &small, "object: value at [1] overflows int8: 300"},
+		{list, &pair, "object: value must have 3 elements, got 2"},
+		{hash, &counts, "object: value at [\"age\"] must be INTEGER, got STRING"},
+		{&Integer{Value: 1}, &counts, "object: value must be HASH, got INTEGER"},
+		{&Integer{Value: 1}, n, "object: ToGo target must be a non-nil pointer, got int"},
+	}
+
+	for _, tt := range tests {
+		err := ToGo(tt.obj, tt.target)
+		if err == nil {
+			t.Errorf("ToGo(%s, %T) succeeded, want error", tt.obj.Inspect(), tt.target)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("ToGo(%s, %T) error = %q, want %q", tt.obj.Inspect(), tt.target, err, tt.expected)
+		}
+	}
+}
+
+// rows is an iterable host value.
+type rows []int64
+
+func (r rows) Type() ObjectType { return "ROWS" }
+func (r rows) Inspect() string  { return "<rows>" }
+func (r rows) Iterate(yield func(Object) bool) error {
+	for _, n := range r {
+		if !yield(&Integer{Value: n}) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestToGoIterable(t *testing.T) {
+	var got []int
+	if err := ToGo(rows{3, 1, 2}, &got); err != nil {
+		t.Fatalf("ToGo failed: %v", err)
+	}
+	if want := []int{3, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ToGo = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package object
+
+import "maps"
+
+// CopyTo binds in dst deep copies of the bindings of e, a top-level
+// environment. Arrays, hashes and functions are copied, and closures are
+// bound to copies of their environments, with e replaced by dst, so that
+// nothing done in dst shows in e and the other way round. Values that
+// cannot change, such as numbers, strings and builtins, and host objects
+// are shared. The settings of dst, such as its grants, are kept.
+func (e *Environment) CopyTo(dst *Environment) {
+	c := &copier{envs: map[*Environment]*Environment{e: dst}, objs: map[Object]Object{}}
+	for name, value := range e.store {
+		dst.store[name] = c.object(value)
+	}
+}
+
+// copier copies each environment and value once, so that values shared or
+// referring to themselves are shared and refer to themselves in the copy.
+type copier struct {
+	envs map[*Environment]*Environment
+	objs map[Object]Object
+}
+
+func (c *copier) env(e *Environment) *Environment {
+	if e == nil {
+		return nil
+	}
+	if clone, ok := c.envs[e]; ok {
+		return clone
+	}
+
+	clone := &Environment{store: make(map[string]Object, len(e.store))}
+	c.envs[e] = clone
+
+	clone.outer = c.env(e.outer)
+	for name, value := range e.store {
+		clone.store[name] = c.object(value)
+	}
+	return clone
+}
+
+func (c *copier) object(obj Object) Object {
+	switch obj.(type) {
+	case *Array, *Hash, *Function:
+		if clone, ok := c.objs[obj]; ok {
+			return clone
+		}
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		clone := &Array{Elements: make([]Object, len(obj.Elements))}
+		c.objs[obj] = clone
+		for i, el := range obj.Elements {
+			clone.Elements[i] = c.object(el)
+		}
+		return clone
+	case *Hash:
+		clone := &Hash{pairs: make([]HashPair, len(obj.pairs)), index: maps.Clone(obj.index)}
+		c.objs[obj] = clone
+		for i, pair := range obj.pairs {
+			clone.pairs[i] = HashPair{Key: pair.Key, Value: c.object(pair.Value)}
+		}
+		return clone
+	case *Function:
+		clone := &Function{Name: obj.Name, Parameters: obj.Parameters, Body: obj.Body}
+		c.objs[obj] = clone
+		clone.Env = c.env(obj.Env)
+		return clone
+	}
+	return obj
+}
@@ -0,0 +1,43 @@
+package object
+
+import "testing"
+
+func TestCopyTo(t *testing.T) {
+	src := NewEnvironment()
+	shared := &Array{Elements: []Object{&Integer{Value: 1}}}
+	self := NewHash()
+	self.Set(&String{Value: "self"}, self)
+	closure := &Function{Name: "f", Env: NewEnclosedEnvironment(src)}
+	closure.Env.Set("shared", shared)
+	src.Set("a", shared)
+	src.Set("b", shared)
+	src.Set("self", self)
+	src.Set("f", closure)
+
+	dst := NewEnvironment()
+	src.CopyTo(dst)
+
+	a, _ := dst.Get("a")
+	b, _ := dst.Get("b")
+	if a == shared || a != b {
+		t.Errorf("arrays should be copied once, and stay shared in the copy")
+	}
+	a.(*Array).Elements[0] = &Integer{Value: 2}
+	if shared.Elements[0].Inspect() != "1" {
+		t.Errorf("change in the copy shows in the original")
+	}
+
+	h, _ := dst.Get("self")
+	if again, _ := h.(*Hash).Get(&String{Value: "self"}); h == self || again != h {
+		t.Errorf("a hash that contains itself should contain its copy")
+	}
+
+	f, _ := dst.Get("f")
+	env := f.(*Function).Env
+	if env == closure.Env || env.outer != dst {
+		t.Errorf("closures should be bound to copies of their environments, nested in dst")
+	}
+	if inner, _ := env.Get("shared"); inner != a {
+		t.Errorf("values shared with a closure should stay shared in the copy")
+	}
+}
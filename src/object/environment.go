@@ -0,0 +1,192 @@
+package object
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// Environment holds the bindings visible to a piece of code. Lookups that
+// miss fall through to the enclosing environment.
+type Environment struct {
+	store   map[string]Object
+	outer   *Environment
+	limiter Limiter // set on top-level environments only
+	grants  *Grants // set on top-level environments only
+
+	logger *slog.Logger // set on top-level environments only
+
+	// the standard streams of scripts; set on top-level environments only
+	stdout io.Writer
+	stderr io.Writer
+	stdin  *bufio.Reader
+}
+
+// NewEnvironment creates an empty top-level environment.
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object)}
+}
+
+// NewEnclosedEnvironment creates an empty environment nested in outer.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// Get looks up name in the environment chain.
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set binds name to val in this environment.
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// Names returns every name visible from this environment, sorted.
+func (e *Environment) Names() []string {
+	seen := map[string]bool{}
+	for env := e; env != nil; env = env.outer {
+		for name := range env.store {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Limiter bounds the work done by the code running in an environment. The
+// evaluator reports every step, function call and allocation to it, and
+// stops with the error it returns.
+type Limiter interface {
+	// Step is called before each node is evaluated.
+	Step() error
+	// Enter is called before a function body runs, and Leave after it
+	// returns. Leave is not called if Enter fails.
+	Enter() error
+	Leave()
+	// Alloc is called with the approximate size in bytes of each string,
+	// array or hash created.
+	Alloc(size int64) error
+}
+
+// Limiter returns the limiter of the top-level environment, or nil if it
+// has none or the environment is nil.
+func (e *Environment) Limiter() Limiter {
+	if e == nil {
+		return nil
+	}
+	return e.top().limiter
+}
+
+// SetLimiter sets the limiter of the top-level environment, which applies
+// to all the code running in it and in the environments nested in it. A
+// nil limiter removes the limits.
+func (e *Environment) SetLimiter(l Limiter) {
+	e.top().limiter = l
+}
+
+// Grants returns the capabilities granted to the code running in the
+// environment, or nil if it is not sandboxed. A nil environment, for a
+// builtin called directly by the host, is not sandboxed either.
+func (e *Environment) Grants() *Grants {
+	if e == nil {
+		return nil
+	}
+	return e.top().grants
+}
+
+// SetGrants sandboxes the code running in the top-level environment and
+// the environments nested in it. Nil grants lift the sandbox.
+func (e *Environment) SetGrants(g *Grants) {
+	e.top().grants = g
+}
+
+// Logger returns the logger that the evaluator writes debug traces of the
+// code running in the environment to, or nil if there is none.
+func (e *Environment) Logger() *slog.Logger {
+	if e == nil {
+		return nil
+	}
+	return e.top().logger
+}
+
+// SetLogger sets the logger of the top-level environment. A nil logger
+// turns traces off.
+func (e *Environment) SetLogger(l *slog.Logger) {
+	e.top().logger = l
+}
+
+// Stdout returns the writer that the code running in the environment
+// prints to. Output is discarded if none was set, and for a nil
+// environment.
+func (e *Environment) Stdout() io.Writer {
+	if e == nil || e.top().stdout == nil {
+		return io.Discard
+	}
+	return e.top().stdout
+}
+
+// SetStdout sets the writer that the code running in the top-level
+// environment and the environments nested in it prints to.
+func (e *Environment) SetStdout(w io.Writer) {
+	e.top().stdout = w
+}
+
+// Stderr is Stdout for error output.
+func (e *Environment) Stderr() io.Writer {
+	if e == nil || e.top().stderr == nil {
+		return io.Discard
+	}
+	return e.top().stderr
+}
+
+// SetStderr is SetStdout for error output.
+func (e *Environment) SetStderr(w io.Writer) {
+	e.top().stderr = w
+}
+
+// Stdin returns the reader that the code running in the environment reads
+// input from. It is empty if none was set, and for a nil environment.
+func (e *Environment) Stdin() *bufio.Reader {
+	if e == nil || e.top().stdin == nil {
+		return bufio.NewReader(strings.NewReader(""))
+	}
+	return e.top().stdin
+}
+
+// SetStdin sets the reader that the code running in the top-level
+// environment and the environments nested in it reads input from. It is
+// buffered, so r should not be read from elsewhere meanwhile.
+func (e *Environment) SetStdin(r io.Reader) {
+	if r == nil {
+		e.top().stdin = nil
+		return
+	}
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	e.top().stdin = br
+}
+
+func (e *Environment) top() *Environment {
+	for e.outer != nil {
+		e = e.outer
+	}
+	return e
+}
@@ -0,0 +1,99 @@
+package object
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strings"
+)
+
+const HASH_OBJ = "HASH"
+
+// HashKey identifies a hashable value. Equal values have equal keys.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the objects that can be used as hash keys.
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash maps hashable keys to values. It remembers the order in which keys
+// were first set, and iterates and prints in that order.
+type Hash struct {
+	pairs []HashPair
+	index map[HashKey]int // position of each key in pairs
+}
+
+// NewHash creates an empty hash.
+func NewHash() *Hash {
+	return &Hash{index: make(map[HashKey]int)}
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Get returns the value stored under key.
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	i, ok := h.index[key.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return h.pairs[i].Value, true
+}
+
+// Set stores value under key, keeping the position of an existing key.
+func (h *Hash) Set(key Hashable, value Object) {
+	hk := key.HashKey()
+	if i, ok := h.index[hk]; ok {
+		h.pairs[i].Value = value
+		return
+	}
+	h.index[hk] = len(h.pairs)
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+// Len returns the number of keys in the hash.
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// Pairs returns the entries of the hash in insertion order. The slice must
+// not be modified.
+func (h *Hash) Pairs() []HashPair { return h.pairs }
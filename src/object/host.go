@@ -0,0 +1,45 @@
+package object
+
+import "errors"
+
+// HostObject is a value defined by the program embedding lars, such as a
+// database handle or a request context. Scripts read its attributes with
+// obj.name, set them with obj.name = value and call its methods with
+// obj.name(args). Its Type and Inspect methods name and print it.
+//
+// Returning an error from any of its methods raises a runtime error at the
+// point of use. An error wrapping ErrNoAttr reports an unknown attribute or
+// method; other errors are reported by their message.
+type HostObject interface {
+	Object
+	GetAttr(name string) (Object, error)
+	SetAttr(name string, value Object) error
+	CallMethod(name string, args ...Object) (Object, error)
+}
+
+// ErrNoAttr is returned by a HostObject that has no attribute or method of
+// the name asked for.
+var ErrNoAttr = errors.New("no such attribute")
+
+// AttrLister is implemented by host objects that can list their attributes
+// and methods. The names are used to suggest a fix when a script asks for
+// one that does not exist.
+type AttrLister interface {
+	AttrNames() []string
+}
+
+// Indexer is implemented by host objects that support obj[key] and
+// obj[key] = value. Index returns NULL, not an error, for a missing key, as
+// hashes do.
+type Indexer interface {
+	Object
+	Index(key Object) (Object, error)
+	SetIndex(key, value Object) error
+}
+
+// Iterable is implemented by host objects that hold a sequence of values.
+// Iterate calls yield with each value in turn until yield returns false.
+type Iterable interface {
+	Object
+	Iterate(yield func(Object) bool) error
+}
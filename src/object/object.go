@@ -1,6 +1,12 @@
 package object
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/salty-max/lars/src/ast"
+)
 
 type ObjectType string
 
@@ -9,8 +15,13 @@ const (
 	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
+	STRING_OBJ       = "STRING"
+	ARRAY_OBJ        = "ARRAY"
+	FUNCTION_OBJ     = "FUNCTION"
+	BUILTIN_OBJ      = "BUILTIN"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ        = "ERROR"
+	EXIT_OBJ         = "EXIT"
 )
 
 type Object interface {
@@ -18,6 +29,22 @@ type Object interface {
 	Inspect() string
 }
 
+// The values of true, false and null. They are shared, so they must never
+// be modified.
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+	NULL  = &Null{}
+)
+
+// NativeBool returns TRUE or FALSE.
+func NativeBool(b bool) *Boolean {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
+
 type Integer struct {
 	Line  int
 	Col   int
@@ -53,6 +80,72 @@ type Null struct {
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
 
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+type Function struct {
+	Name       string // the name it was first bound to, "" if none
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+// BuiltinFunction is the Go implementation of a builtin. env is the
+// environment of the caller, which holds the interpreter settings such as
+// its grants; it is nil when the host calls the builtin directly. Errors it
+// returns without a position are reported at the call site.
+type BuiltinFunction func(env *Environment, args ...Object) Object
+
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return fmt.Sprintf("builtin %s", b.Name) }
+
 type ReturnValue struct {
 	Value Object
 }
@@ -64,8 +157,40 @@ type Error struct {
 	Message string
 	Line    int
 	Col     int
+	Hint    string // optional help note, e.g. a spelling suggestion
+
+	// Err is the Go error behind the message, if any, such as the limit
+	// that stopped the evaluation. Hosts test for it with errors.Is.
+	Err error
+
+	// Stack lists the function calls the error unwound through, innermost
+	// first.
+	Stack []Frame
+}
+
+// Frame is a call of a lars function: the name of the function, "" if it
+// has none, and the position of the call, 0:0 if it was made by the host.
+type Frame struct {
+	Function string
+	Line     int
+	Col      int
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 
-func (e *Error) Inspect() string { return fmt.Sprintf("Error (%d:%d) -> %s", e.Line, e.Col, e.Message) }
+func (e *Error) Inspect() string {
+	msg := fmt.Sprintf("Error (%d:%d) -> %s", e.Line, e.Col, e.Message)
+	if e.Hint != "" {
+		msg += "\n\thelp: " + e.Hint
+	}
+	return msg
+}
+
+// Exit is produced by the exit builtin. Like an error it unwinds the whole
+// evaluation, and the driver turns it into the process exit code.
+type Exit struct {
+	Code int
+}
+
+func (e *Exit) Type() ObjectType { return EXIT_OBJ }
+func (e *Exit) Inspect() string  { return fmt.Sprintf("exit(%d)", e.Code) }
@@ -2,37 +2,80 @@ package parser
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/salty-max/lars/src/ast"
 	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/suggest"
 	"github.com/salty-max/lars/src/token"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // x.y = z
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
+	INDEX       // array[index] or object.name
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:      EQUALS,
-	token.NOT_EQ:  EQUALS,
-	token.LT:      LESSGREATER,
-	token.GT:      LESSGREATER,
-	token.LTE:     LESSGREATER,
-	token.GTE:     LESSGREATER,
-	token.PLUS:    SUM,
-	token.MINUS:   SUM,
-	token.SLASH:   PRODUCT,
-	token.STAR:    PRODUCT,
-	token.PERCENT: PRODUCT,
-	token.LPAREN:  CALL,
+	token.ASSIGN:   ASSIGN,
+	token.EQ:       EQUALS,
+	token.NOT_EQ:   EQUALS,
+	token.LT:       LESSGREATER,
+	token.GT:       LESSGREATER,
+	token.LTE:      LESSGREATER,
+	token.GTE:      LESSGREATER,
+	token.PLUS:     SUM,
+	token.MINUS:    SUM,
+	token.SLASH:    PRODUCT,
+	token.STAR:     PRODUCT,
+	token.PERCENT:  PRODUCT,
+	token.LPAREN:   CALL,
+	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
+}
+
+// maxErrors caps the number of errors reported for a single program.
+const maxErrors = 10
+
+// statementKeywords are the tokens that can only begin a statement. The
+// parser resynchronises in front of them after an error.
+var statementKeywords = map[token.TokenType]bool{
+	token.LET:      true,
+	token.CONST:    true,
+	token.RETURN:   true,
+	token.FOR:      true,
+	token.WHILE:    true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+}
+
+// keywordAliases maps keywords borrowed from other languages to the lars
+// keyword they stand for.
+var keywordAliases = map[string]string{
+	"function": "fn",
+	"func":     "fn",
+	"def":      "fn",
+	"var":      "let",
+	"elsif":    "elif",
+	"elseif":   "elif",
+}
+
+// Precedence returns the binding power of t when it is used as an infix
+// operator, or LOWEST if it is not one.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
 }
 
 type (
@@ -44,6 +87,12 @@ type ParserError struct {
 	Msg  string
 	Line int
 	Col  int
+	Hint string // optional help note, e.g. a spelling suggestion
+}
+
+type errorPos struct {
+	line int
+	col  int
 }
 
 type Parser struct {
@@ -52,12 +101,25 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
+	// panicking is set when an error is reported and cleared once the
+	// parser has skipped to the next statement boundary. Errors reported
+	// in between are follow-on noise and are dropped.
+	panicking bool
+	seen      map[errorPos]bool
+	tooMany   bool
+
+	// misspelled is the keyword that a statement on misspelledLine seems
+	// to have been meant to start with, if any. It is offered as a hint on
+	// the errors reported on that line, up to the next semicolon.
+	misspelled     string
+	misspelledLine int
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []ParserError{}}
+	p := &Parser{l: l, errors: []ParserError{}, seen: make(map[errorPos]bool)}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
@@ -71,6 +133,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.NULL, p.parseNullLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -85,6 +150,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LTE, p.parseInfixExpression)
 	p.registerInfix(token.GTE, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -114,11 +182,17 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
-	for p.curToken.Type != token.EOF {
+	for p.curToken.Type != token.EOF && !p.tooMany {
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize()
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+		if p.curTokenIs(token.SEMICOLON) {
+			// later errors are not the misspelled statement's
+			p.misspelled = ""
+		}
 		p.nextToken()
 	}
 
@@ -128,7 +202,10 @@ func (p *Parser) ParseProgram() *ast.Program {
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET, token.CONST:
-		return p.parseVarDeclStatement()
+		if stmt := p.parseVarDeclStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		return p.parseReturnStatement()
 	default:
@@ -136,6 +213,36 @@ func (p *Parser) parseStatement() ast.Statement {
 	}
 }
 
+// synchronize skips tokens until the parser reaches a statement boundary:
+// a semicolon, the closing brace of the enclosing block, or a keyword that
+// starts a new statement. Braces opened while skipping are balanced so that
+// a broken block is discarded as a whole.
+func (p *Parser) synchronize() {
+	p.panicking = false
+	depth := 0
+
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 && (p.peekTokenIs(token.RBRACE) || statementKeywords[p.peekToken.Type]) {
+			return
+		}
+
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseVarDeclStatement() *ast.VarDeclStatement {
 	stmt := &ast.VarDeclStatement{Token: p.curToken}
 
@@ -185,11 +292,62 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
+	} else {
+		p.checkMisspelledKeyword(stmt.Expression)
 	}
 
 	return stmt
 }
 
+// checkMisspelledKeyword notes the keyword an expression statement was
+// likely meant to start with, when it starts with an identifier resembling
+// one and more code follows on the same line, as in `lett x = 5, y = 6`.
+// The code may well be valid, so nothing is reported; should a parse error
+// follow on that line, it gets the keyword as a hint.
+func (p *Parser) checkMisspelledKeyword(expr ast.Expression) {
+	if p.peekToken.Line != p.curToken.Line ||
+		p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		return
+	}
+
+	var ident *ast.Identifier
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		ident = expr
+	case *ast.CallExpression:
+		ident, _ = expr.Function.(*ast.Identifier)
+	}
+	if ident == nil {
+		return
+	}
+
+	if keyword, ok := suggestKeyword(ident.Value); ok {
+		p.misspelled, p.misspelledLine = keyword, p.curToken.Line
+	}
+}
+
+// suggestKeyword returns the keyword that name was most likely meant to be.
+func suggestKeyword(name string) (string, bool) {
+	if keyword, ok := keywordAliases[name]; ok {
+		return keyword, true
+	}
+
+	candidates := token.Keywords()
+	for alias := range keywordAliases {
+		candidates = append(candidates, alias)
+	}
+	sort.Strings(candidates)
+
+	match, ok := suggest.Closest(name, candidates)
+	if !ok {
+		return "", false
+	}
+	if keyword, ok := keywordAliases[match]; ok {
+		return keyword, true
+	}
+	return match, true
+}
+
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
@@ -228,7 +386,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, ParserError{Msg: msg, Line: p.curToken.Line, Col: p.curToken.Col})
+	p.addError(msg, p.curToken.Line, p.curToken.Col)
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -326,32 +484,117 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
 	expr := &ast.CallExpression{Token: p.curToken, Function: fn}
-	expr.Arguments = p.parseCallArguments()
+	expr.Arguments = p.parseExpressionList(token.RPAREN)
+	expr.Rparen = p.curToken
 	return expr
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbracket = p.curToken
+	return array
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []ast.HashPair{}}
 
-	if p.peekTokenIs(token.RPAREN) {
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	hash.Rbrace = p.curToken
+
+	return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	expr := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	expr.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return expr
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	expr := &ast.MemberExpression{Token: p.curToken, Object: left}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expr.Property = p.curToken
+
+	return expr
+}
+
+// parseAssignExpression parses an assignment to an attribute or an index.
+// Assignment is right-associative, so a.x = b.x = 1 sets both.
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	expr := &ast.AssignExpression{Token: p.curToken, Target: left}
+
+	switch left.(type) {
+	case *ast.MemberExpression, *ast.IndexExpression:
+	case *ast.Identifier:
+		p.report(ParserError{
+			Msg:  fmt.Sprintf("cannot assign to variable %s", left),
+			Line: p.curToken.Line,
+			Col:  p.curToken.Col,
+			Hint: fmt.Sprintf("use `let %s = ...` to bind a new value", left),
+		})
+		return nil
+	default:
+		p.addError(fmt.Sprintf("cannot assign to %s", left), p.curToken.Line, p.curToken.Col)
+		return nil
+	}
+
+	p.nextToken()
+	expr.Value = p.parseExpression(LOWEST)
+
+	return expr
+}
+
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
 		p.nextToken()
-		return args
+		return list
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
 
-	return args
+	return list
 }
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
@@ -360,14 +603,20 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 
 	p.nextToken()
 
-	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) && !p.tooMany {
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize()
+		} else if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
 
+	if p.curTokenIs(token.RBRACE) {
+		block.Rbrace = p.curToken
+	}
+
 	return block
 }
 
@@ -393,10 +642,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(
-			p.errors,
-			ParserError{Msg: msg, Line: p.curToken.Line, Col: p.curToken.Col},
-		)
+		p.addError(msg, p.curToken.Line, p.curToken.Col)
 		return nil
 	}
 
@@ -411,10 +657,7 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
-		p.errors = append(
-			p.errors,
-			ParserError{Msg: msg, Line: p.curToken.Line, Col: p.curToken.Col},
-		)
+		p.addError(msg, p.curToken.Line, p.curToken.Col)
 		return nil
 	}
 
@@ -423,6 +666,56 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	lit := &ast.StringLiteral{Token: p.curToken}
+
+	value, err := unescape(p.curToken.Literal)
+	if err != nil {
+		msg := fmt.Sprintf("invalid string literal %q: %s", p.curToken.Literal, err)
+		p.addError(msg, p.curToken.Line, p.curToken.Col)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+// unescape resolves the escape sequences \n, \t, \r, \" and \\ in s.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("trailing backslash")
+		}
+
+		switch s[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case '"', '\\':
+			out.WriteByte(s[i])
+		default:
+			return "", fmt.Errorf("unknown escape sequence \\%c", s[i])
+		}
+	}
+
+	return out.String(), nil
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -465,5 +758,37 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, ParserError{Msg: msg, Line: p.peekToken.Line, Col: p.peekToken.Col})
+	p.addError(msg, p.peekToken.Line, p.peekToken.Col)
+}
+
+func (p *Parser) addError(msg string, line, col int) {
+	p.report(ParserError{Msg: msg, Line: line, Col: col})
+}
+
+// report records an error and puts the parser in panic mode. Errors
+// reported while already panicking, or at a position that already has an
+// error, are dropped. Once maxErrors is reached parsing stops.
+func (p *Parser) report(err ParserError) {
+	if p.panicking || p.tooMany {
+		return
+	}
+	p.panicking = true
+
+	pos := errorPos{line: err.Line, col: err.Col}
+	if p.seen[pos] {
+		return
+	}
+	p.seen[pos] = true
+
+	if err.Hint == "" && p.misspelled != "" && err.Line == p.misspelledLine {
+		err.Hint = fmt.Sprintf("did you mean `%s`?", p.misspelled)
+	}
+
+	if len(p.errors) == maxErrors {
+		p.errors = append(p.errors, ParserError{Msg: "too many errors", Line: err.Line, Col: err.Col})
+		p.tooMany = true
+		return
+	}
+
+	p.errors = append(p.errors, err)
 }
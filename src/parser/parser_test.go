@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/salty-max/lars/src/ast"
 	"github.com/salty-max/lars/src/lexer"
 	"github.com/salty-max/lars/src/log"
+	"github.com/salty-max/lars/src/token"
 )
 
 func TestVarDeclStatements(t *testing.T) {
@@ -628,6 +630,13 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))",
 		},
 		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
+		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"a.b.c", "((a.b).c)"},
+		{"-a.b * c", "((-(a.b)) * c)"},
+		{"a.b(1).c[0]", "(((a.b)(1).c)[0])"},
+		{"a.x = b.x = 1 + 2", "((a.x) = ((b.x) = (1 + 2)))"},
+		{"a[0] = b == c", "((a[0]) = (b == c))"},
 	}
 
 	for _, tt := range tests {
@@ -642,6 +651,336 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestStringLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world";`, "hello world"},
+		{`"tab\there";`, "tab\there"},
+		{`"quote \" and backslash \\";`, `quote " and backslash \`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
+	}
+
+	p := New(lexer.New(`"bad \q escape"`))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error for an unknown escape. got=%+v", p.Errors())
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, _ := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{}`, `{}`},
+		{`{"one": 1, "two": 2}`, `{"one": 1, "two": 2}`},
+		{`{"one": 0 + 1, two: 10 - 8, 3: [3],}`, `{"one": (0 + 1), two: (10 - 8), 3: [3]}`},
+		{`{true: {"nested": null}}`, `{true: {"nested": null}}`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, _ := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp not ast.HashLiteral. got=%T", stmt.Expression)
+		}
+
+		if hash.String() != tt.expected {
+			t.Errorf("wrong hash. want=%q, got=%q", tt.expected, hash.String())
+		}
+		if hash.Rbrace.Type != token.RBRACE {
+			t.Errorf("hash.Rbrace not set. got=%+v", hash.Rbrace)
+		}
+	}
+}
+
+func TestParsingHashLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a" 1}`, "(1:6) expected next token to be :, got INT instead"},
+		{`{"a": 1 "b": 2}`, "(1:9) expected next token to be ,, got STRING instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("input %q: expected errors", tt.input)
+			continue
+		}
+		got := fmt.Sprintf("(%d:%d) %s", errors[0].Line, errors[0].Col, errors[0].Msg)
+		if got != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, _ := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestParsingMemberExpressions(t *testing.T) {
+	input := "db.query(1)"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, _ := program.Statements[0].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.CallExpression. got=%T", stmt.Expression)
+	}
+	member, ok := call.Function.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("function not *ast.MemberExpression. got=%T", call.Function)
+	}
+
+	if !testIdentifier(t, member.Object, "db") {
+		return
+	}
+	if member.Property.Literal != "query" || member.Property.Col != 4 {
+		t.Errorf("wrong property. want=query at column 4, got=%s at column %d",
+			member.Property.Literal, member.Property.Col)
+	}
+}
+
+func TestParsingAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input        string
+		expected     string
+		expectedHint string
+	}{
+		{"x = 1", "(1:3) cannot assign to variable x", "use `let x = ...` to bind a new value"},
+		{"f() = 1", "(1:5) cannot assign to f()", ""},
+		{"a.1", "(1:3) expected next token to be IDENT, got INT instead", ""},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("input %q: expected errors", tt.input)
+			continue
+		}
+		got := fmt.Sprintf("(%d:%d) %s", errors[0].Line, errors[0].Col, errors[0].Msg)
+		if got != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+		if errors[0].Hint != tt.expectedHint {
+			t.Errorf("input %q: wrong hint. want=%q, got=%q", tt.input, tt.expectedHint, errors[0].Hint)
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedStmts  int
+	}{
+		{
+			"let = 5; let y = 10;",
+			[]string{"(1:5) expected next token to be IDENT, got = instead"},
+			1,
+		},
+		{
+			"let x 5; let y = 10; y;",
+			[]string{"(1:7) expected next token to be =, got INT instead"},
+			2,
+		},
+		{
+			"let x = ; let y = 10;",
+			[]string{"(1:9) no prefix parse function for ; found"},
+			1,
+		},
+		{
+			"if (x { x } else { y }; let z = 1;",
+			[]string{"(1:7) expected next token to be ), got { instead"},
+			1,
+		},
+		{
+			"fn(x) { let = 5; x } let y = 2",
+			[]string{"(1:13) expected next token to be IDENT, got = instead"},
+			2,
+		},
+		{
+			"fn(x, { x }; 5",
+			[]string{"(1:9) expected next token to be ), got IDENT instead"},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := []string{}
+		for _, err := range p.Errors() {
+			errors = append(errors, fmt.Sprintf("(%d:%d) %s", err.Line, err.Col, err.Msg))
+		}
+
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("input %q: wrong number of errors. want=%q, got=%q",
+				tt.input, tt.expectedErrors, errors)
+			continue
+		}
+
+		for i, err := range errors {
+			if err != tt.expectedErrors[i] {
+				t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expectedErrors[i], err)
+			}
+		}
+
+		if len(program.Statements) != tt.expectedStmts {
+			t.Errorf("input %q: wrong number of statements. want=%d, got=%d",
+				tt.input, tt.expectedStmts, len(program.Statements))
+		}
+
+		for i, stmt := range program.Statements {
+			if decl, ok := stmt.(*ast.VarDeclStatement); ok && decl == nil {
+				t.Errorf("input %q: statement %d is a nil *ast.VarDeclStatement", tt.input, i)
+			}
+		}
+	}
+}
+
+func TestMisspelledKeywordHints(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedMsg  string
+		expectedHint string
+	}{
+		{"def add(a, b): a + b", "no prefix parse function for : found", "did you mean `fn`?"},
+		{"var x: int = 5", "no prefix parse function for : found", "did you mean `let`?"},
+		{"if (x) { 1 } esle { 2 }", "expected next token to be :, got } instead", "did you mean `else`?"},
+		{"funtion add(a, b) { a + b }", "expected next token to be :, got } instead", "did you mean `fn`?"},
+		{"retrun 5; let = 1", "expected next token to be IDENT, got = instead", ""},
+		{"retrun 5\nlet = 1", "expected next token to be IDENT, got = instead", ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("input %q: expected 1 error. got=%+v", tt.input, errors)
+			continue
+		}
+
+		if errors[0].Msg != tt.expectedMsg {
+			t.Errorf("input %q: wrong message. want=%q, got=%q", tt.input, tt.expectedMsg, errors[0].Msg)
+		}
+		if errors[0].Hint != tt.expectedHint {
+			t.Errorf("input %q: wrong hint. want=%q, got=%q", tt.input, tt.expectedHint, errors[0].Hint)
+		}
+	}
+
+	// identifiers resembling keywords are no error when the code parses
+	for _, input := range []string{
+		"let x = 5\nx\ny",
+		"retrun 5;",
+		"let retur = fn(x) { x }; retur (1) retur(2)",
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("input %q: unexpected errors %+v", input, p.Errors())
+		}
+	}
+}
+
+func TestParserErrorLimit(t *testing.T) {
+	input := strings.Repeat("let = 1;\n", maxErrors+5)
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != maxErrors+1 {
+		t.Fatalf("wrong number of errors. want=%d, got=%d", maxErrors+1, len(errors))
+	}
+
+	if last := errors[len(errors)-1]; last.Msg != "too many errors" {
+		t.Errorf("last error is not 'too many errors'. got=%q", last.Msg)
+	}
+}
+
 func testVarDeclStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" && s.TokenLiteral() != "const" {
 		t.Errorf("s.TokenLiteral not 'let' or 'const'. got=%q", s.TokenLiteral())
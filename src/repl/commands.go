@@ -0,0 +1,193 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/evaluator"
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/log"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/parser"
+	"github.com/salty-max/lars/src/suggest"
+)
+
+// command is a REPL meta-command, entered as a line starting with a colon.
+type command struct {
+	name string
+	args string
+	help string
+	run  func(s *session, arg string) bool // reports whether to exit
+}
+
+// commands lists the meta-commands in the order :help shows them. It is
+// filled in init, since :help refers back to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{":help", "", "show this help", (*session).help},
+		{":quit", "", "leave the REPL", (*session).quit},
+		{":type", "expr", "show the runtime type of expr", (*session).typeOf},
+		{":ast", "[expr]", "show the AST of expr, or toggle AST dumps", (*session).ast},
+		{":tokens", "[expr]", "show the tokens of expr, or toggle token dumps", (*session).tokens},
+		{":time", "expr", "evaluate expr and report its duration and allocations", (*session).time},
+		{":env", "", "list the session bindings and their types", (*session).listEnv},
+		{":reset", "", "clear the session", (*session).reset},
+		{":load", "file", "evaluate file into the session", (*session).load},
+		{":save", "file", "write the session inputs to file", (*session).saveTo},
+	}
+}
+
+// command runs the meta-command line. It reports whether the REPL should
+// exit.
+func (s *session) command(line string) bool {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if cmd.args != "" && !strings.HasPrefix(cmd.args, "[") && arg == "" {
+				s.error(fmt.Sprintf("usage: %s %s", cmd.name, cmd.args))
+				return false
+			}
+			return cmd.run(s, arg)
+		}
+		names = append(names, cmd.name)
+	}
+
+	msg := fmt.Sprintf("unknown command %s", name)
+	if match, ok := suggest.Closest(name, names); ok {
+		msg += fmt.Sprintf("\n\thelp: did you mean `%s`?", match)
+	}
+	s.error(msg)
+
+	return false
+}
+
+func (s *session) help(string) bool {
+	fmt.Fprintln(s.out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(s.out, "  %-16s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.help)
+	}
+	return false
+}
+
+func (s *session) quit(string) bool {
+	return true
+}
+
+func (s *session) typeOf(expr string) bool {
+	program := s.parse(expr)
+	if program == nil {
+		return false
+	}
+
+	evaluated := evaluator.EvalContext(context.Background(), program, s.env, evaluator.Limits{})
+	switch {
+	case evaluated == nil:
+		fmt.Fprintln(s.out, object.NULL_OBJ)
+	case evaluated.Type() == object.ERROR_OBJ:
+		s.error(evaluated.Inspect())
+	default:
+		fmt.Fprintln(s.out, evaluated.Type())
+	}
+
+	return false
+}
+
+func (s *session) ast(expr string) bool {
+	if expr == "" {
+		s.showAST = !s.showAST
+		fmt.Fprintf(s.out, "AST dump %s\n", onOff(s.showAST))
+		return false
+	}
+
+	if program := s.parse(expr); program != nil {
+		ast.Dump(s.out, program)
+	}
+	return false
+}
+
+func (s *session) tokens(expr string) bool {
+	if expr == "" {
+		s.showTokens = !s.showTokens
+		fmt.Fprintf(s.out, "token dump %s\n", onOff(s.showTokens))
+		return false
+	}
+
+	printTokens(s.out, s.colors, expr)
+	return false
+}
+
+func (s *session) time(expr string) bool {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	exit := s.run(expr)
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	fmt.Fprintln(s.out, s.colors.Colorize(log.CYAN, fmt.Sprintf(
+		"took %s, %d allocations, %d bytes",
+		elapsed, after.Mallocs-before.Mallocs, after.TotalAlloc-before.TotalAlloc,
+	)))
+
+	return exit
+}
+
+func (s *session) listEnv(string) bool {
+	s.printEnv()
+	return false
+}
+
+func (s *session) reset(string) bool {
+	s.env = s.newEnvironment()
+	s.transcript = nil
+	fmt.Fprintln(s.out, "session reset")
+	return false
+}
+
+// load evaluates the file at path as if its lines were typed in, so that
+// a saved session replays input by input, with _ bound along the way.
+func (s *session) load(path string) bool {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		s.error(err.Error())
+		return false
+	}
+
+	for _, input := range splitInputs(string(src)) {
+		if s.run(input) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *session) saveTo(path string) bool {
+	if err := s.save(path); err != nil {
+		s.error(err.Error())
+	}
+	return false
+}
+
+// parse parses input, printing any errors. It returns nil if input does
+// not parse.
+func (s *session) parse(input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, s.colors, p.Errors())
+		return nil
+	}
+	return program
+}
@@ -0,0 +1,70 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/salty-max/lars/src/log"
+)
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{":type 1 + 2", "INTEGER\n"},
+		{`:type "lars"`, "STRING\n"},
+		{":type fn(x) { x }", "FUNCTION\n"},
+		{":ast -x", "Program [1:1-1:2]\n  ExpressionStatement [1:1-1:2]\n    PrefixExpression - [1:1-1:2]\n      Identifier x [1:2-1:2]\n"},
+		{":ast", "AST dump on\n"},
+		{":tokens", "token dump on\n"},
+		{":type", "usage: :type expr\n"},
+		{":load", "usage: :load file\n"},
+		{":hepl", "unknown command :hepl\n\thelp: did you mean `:help`?\n"},
+		{":nothing", "unknown command :nothing\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		s := newSession(&out, log.ColorNever)
+
+		if s.command(tt.line) {
+			t.Errorf("command %q should not exit", tt.line)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("command %q: wrong output. want=%q, got=%q", tt.line, tt.expected, out.String())
+		}
+	}
+}
+
+func TestHelpListsEveryCommand(t *testing.T) {
+	var out bytes.Buffer
+	newSession(&out, log.ColorNever).command(":help")
+
+	for _, cmd := range commands {
+		if !strings.Contains(out.String(), cmd.name) {
+			t.Errorf(":help does not mention %s", cmd.name)
+		}
+	}
+}
+
+func TestQuitCommand(t *testing.T) {
+	var out bytes.Buffer
+	if !newSession(&out, log.ColorNever).command(":quit") {
+		t.Errorf(":quit should exit")
+	}
+}
+
+func TestTimeCommand(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, log.ColorNever)
+	s.command(":time let x = 2;")
+
+	if !strings.Contains(out.String(), "took ") || !strings.Contains(out.String(), "allocations") {
+		t.Errorf("wrong :time output %q", out.String())
+	}
+	if _, ok := s.env.Get("x"); !ok {
+		t.Errorf(":time should evaluate into the session")
+	}
+}
@@ -0,0 +1,440 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/salty-max/lars/src/term"
+)
+
+// errInterrupted is returned by readLine when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads the REPL input a line at a time.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// newLineReader returns a line editor when in and out are both terminals,
+// and a plain line reader otherwise, for example when input is piped.
+func newLineReader(in io.Reader, out io.Writer, complete func(string) []string) lineReader {
+	inFile, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(inFile.Fd())) {
+		return &scanReader{scanner: bufio.NewScanner(in), out: out}
+	}
+	if outFile, ok := out.(*os.File); !ok || !term.IsTerminal(int(outFile.Fd())) {
+		return &scanReader{scanner: bufio.NewScanner(in), out: out}
+	}
+
+	return &terminalReader{
+		fd:     int(inFile.Fd()),
+		editor: newEditor(in, out, loadHistory(historyPath()), complete),
+	}
+}
+
+// scanReader reads lines without any editing support.
+type scanReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scanReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// terminalReader runs the line editor with the terminal in raw mode. The
+// terminal is restored between lines, so program output prints normally.
+type terminalReader struct {
+	fd     int
+	editor *editor
+}
+
+func (r *terminalReader) readLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(r.fd, state)
+
+	return r.editor.readLine(prompt)
+}
+
+// Keys the editor handles.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyNewline   = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Keys sent as escape sequences are decoded to runes past the Unicode range.
+const (
+	keyUp = unicode.MaxRune + 1 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyForwardDelete
+)
+
+// editor is a minimal line editor for terminals in raw mode. It supports
+// cursor movement, Emacs-style kill keys, history browsing, reverse search
+// and tab completion.
+type editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  *history
+	complete func(prefix string) []string
+
+	prompt string
+	buf    []rune
+	pos    int // cursor position in buf
+}
+
+func newEditor(in io.Reader, out io.Writer, h *history, complete func(string) []string) *editor {
+	return &editor{in: bufio.NewReader(in), out: out, history: h, complete: complete}
+}
+
+// readLine reads one line, showing prompt in front of it. It returns
+// io.EOF on Ctrl-D at an empty line and errInterrupted on Ctrl-C.
+func (e *editor) readLine(prompt string) (string, error) {
+	e.prompt, e.buf, e.pos = prompt, nil, 0
+
+	// index of the history entry shown, and the line being entered while
+	// browsing history
+	index, pending := len(e.history.entries), ""
+
+	e.refresh()
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case keyEnter, keyNewline:
+			return e.accept(), nil
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case keyCtrlA, keyHome:
+			e.pos = 0
+		case keyCtrlE, keyEnd:
+			e.pos = len(e.buf)
+		case keyCtrlB, keyLeft:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyCtrlF, keyRight:
+			if e.pos < len(e.buf) {
+				e.pos++
+			}
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyForwardDelete:
+			e.deleteAt(e.pos)
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlU:
+			e.buf = e.buf[e.pos:]
+			e.pos = 0
+		case keyCtrlW:
+			start := e.pos
+			for start > 0 && unicode.IsSpace(e.buf[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(e.buf[start-1]) {
+				start--
+			}
+			e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+			e.pos = start
+		case keyCtrlL:
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case keyCtrlP, keyUp:
+			if index > 0 {
+				if index == len(e.history.entries) {
+					pending = string(e.buf)
+				}
+				index--
+				e.set(e.history.entries[index])
+			}
+		case keyCtrlN, keyDown:
+			if index < len(e.history.entries) {
+				index++
+				if index == len(e.history.entries) {
+					e.set(pending)
+				} else {
+					e.set(e.history.entries[index])
+				}
+			}
+		case keyCtrlR:
+			line, submit, err := e.search()
+			if err != nil {
+				return "", err
+			}
+			e.set(line)
+			if submit {
+				e.refresh()
+				return e.accept(), nil
+			}
+		case keyTab:
+			e.completeWord()
+		default:
+			if unicode.IsPrint(key) {
+				e.insert(key)
+			}
+		}
+
+		e.refresh()
+	}
+}
+
+// readKey reads one key press, decoding the escape sequences of arrow and
+// editing keys.
+func (e *editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+
+	// a lone escape is followed by nothing
+	if e.in.Buffered() == 0 {
+		return keyEscape, nil
+	}
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyEscape, nil
+	}
+
+	var param []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case r >= '0' && r <= '9' || r == ';':
+			param = append(param, r)
+			continue
+		case r == 'A':
+			return keyUp, nil
+		case r == 'B':
+			return keyDown, nil
+		case r == 'C':
+			return keyRight, nil
+		case r == 'D':
+			return keyLeft, nil
+		case r == 'H':
+			return keyHome, nil
+		case r == 'F':
+			return keyEnd, nil
+		case r == '~':
+			switch string(param) {
+			case "1", "7":
+				return keyHome, nil
+			case "4", "8":
+				return keyEnd, nil
+			case "3":
+				return keyForwardDelete, nil
+			}
+		}
+
+		// an unknown sequence is ignored
+		return keyEscape, nil
+	}
+}
+
+// accept ends the line being edited, adds it to history and returns it.
+func (e *editor) accept() string {
+	io.WriteString(e.out, "\r\n")
+	line := string(e.buf)
+	e.history.add(line)
+	return line
+}
+
+func (e *editor) insert(runes ...rune) {
+	tail := append(runes, e.buf[e.pos:]...)
+	e.buf = append(e.buf[:e.pos], tail...)
+	e.pos += len(runes)
+}
+
+func (e *editor) deleteAt(pos int) {
+	if pos < len(e.buf) {
+		e.buf = append(e.buf[:pos], e.buf[pos+1:]...)
+	}
+}
+
+// set replaces the line with line, with the cursor at its end.
+func (e *editor) set(line string) {
+	e.buf = []rune(line)
+	e.pos = len(e.buf)
+}
+
+// refresh redraws the line and moves the cursor in place.
+func (e *editor) refresh() {
+	e.draw(e.prompt+string(e.buf), len(e.buf)-e.pos)
+}
+
+// draw replaces the terminal line with s and puts the cursor back before
+// the last back runes.
+func (e *editor) draw(s string, back int) {
+	io.WriteString(e.out, "\r"+s+"\x1b[K")
+	if back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// search runs an incremental reverse search through history. It returns
+// the line found, or the original line if the search is cancelled, and
+// whether the user asked to submit it right away.
+func (e *editor) search() (line string, submit bool, err error) {
+	original := string(e.buf)
+
+	var query []rune
+	index := len(e.history.entries) // entry matched, or len if none
+	find := func(from int) {
+		for i := min(from, len(e.history.entries)-1); i >= 0; i-- {
+			if strings.Contains(e.history.entries[i], string(query)) {
+				index = i
+				return
+			}
+		}
+	}
+
+	for {
+		match := ""
+		if index < len(e.history.entries) {
+			match = e.history.entries[index]
+		}
+		e.draw(fmt.Sprintf("(reverse-i-search)`%s': %s", string(query), match), 0)
+
+		key, err := e.readKey()
+		if err != nil {
+			return "", false, err
+		}
+
+		switch key {
+		case keyCtrlR:
+			find(index - 1)
+		case keyBackspace, keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				index = len(e.history.entries)
+				find(index)
+			}
+		case keyCtrlG, keyCtrlC, keyEscape:
+			return original, false, nil
+		case keyEnter, keyNewline:
+			return match, true, nil
+		default:
+			if !unicode.IsPrint(key) {
+				return match, false, nil
+			}
+			query = append(query, key)
+			find(index)
+		}
+	}
+}
+
+// completeWord completes the word before the cursor. A single candidate is
+// inserted; otherwise their common prefix is, or they are listed if there
+// is no common prefix to add.
+func (e *editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+
+	start := e.pos
+	for start > 0 && isWordRune(e.buf[start-1]) {
+		start--
+	}
+	// meta-commands are completed as a whole
+	if start == 1 && e.buf[0] == ':' {
+		start = 0
+	}
+	prefix := string(e.buf[start:e.pos])
+	if prefix == "" {
+		return
+	}
+
+	var candidates []string
+	seen := map[string]bool{}
+	for _, c := range e.complete(prefix) {
+		if strings.HasPrefix(c, prefix) && !seen[c] {
+			seen[c] = true
+			candidates = append(candidates, c)
+		}
+	}
+	sort.Strings(candidates)
+
+	switch len(candidates) {
+	case 0:
+		io.WriteString(e.out, "\a")
+	case 1:
+		e.insert([]rune(candidates[0][len(prefix):])...)
+	default:
+		common := commonPrefix(candidates)
+		if len(common) > len(prefix) {
+			e.insert([]rune(common[len(prefix):])...)
+			return
+		}
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,157 @@
+package repl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string, entries ...string) *editor {
+	complete := func(string) []string {
+		return []string{"let", "len", "length", "false", ":help", ":history"}
+	}
+	return newEditor(strings.NewReader(input), io.Discard, &history{entries: entries}, complete)
+}
+
+func TestEditorKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		history  []string
+		expected string
+	}{
+		{"plain", "let x\r", nil, "let x"},
+		{"backspace", "lex\x7ft\r", nil, "let"},
+		{"ctrl-a", "bc\x01a\r", nil, "abc"},
+		{"ctrl-e", "bc\x01a\x05d\r", nil, "abcd"},
+		{"ctrl-k", "abcdef\x1b[D\x1b[D\x1b[D\x0b\r", nil, "abc"},
+		{"ctrl-u", "abcdef\x1b[D\x1b[D\x15\r", nil, "ef"},
+		{"ctrl-w", "let answer = 42\x17\x17\r", nil, "let answer "},
+		{"arrows", "ac\x1b[Db\x1b[C!\r", nil, "abc!"},
+		{"home and end", "b\x1b[Ha\x1b[Fc\r", nil, "abc"},
+		{"forward delete", "abc\x1b[H\x1b[3~\r", nil, "bc"},
+		{"ctrl-d deletes", "abc\x01\x04\r", nil, "bc"},
+		{"history up", "\x1b[A\x1b[A\r", []string{"first", "second"}, "first"},
+		{"history down", "draft\x1b[A\x1b[A\x1b[B\x1b[B\r", []string{"first", "second"}, "draft"},
+		{"reverse search", "\x12fi\r", []string{"first", "second", "fifth"}, "fifth"},
+		{"reverse search again", "\x12fi\x12\r", []string{"first", "second", "fifth"}, "first"},
+		{"reverse search edit", "\x12sec\x05!\r", []string{"first", "second"}, "second!"},
+		{"reverse search cancel", "x\x12sec\x07\r", []string{"first", "second"}, "x"},
+		{"complete single", "fa\t\r", nil, "false"},
+		{"complete common prefix", "le\t\r", nil, "le"},
+		{"complete longer common prefix", "len\t\r", nil, "len"},
+		{"complete after text", "1 + fa\t\r", nil, "1 + false"},
+		{"complete command", ":he\t\r", nil, ":help"},
+		{"no completion", "zz\t\r", nil, "zz"},
+	}
+
+	for _, tt := range tests {
+		e := newTestEditor(tt.input, tt.history...)
+
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("%s: wrong line. want=%q, got=%q", tt.name, tt.expected, line)
+		}
+	}
+}
+
+func TestEditorCompletionListsCandidates(t *testing.T) {
+	var out bytes.Buffer
+	e := newEditor(strings.NewReader("le\t\r"), &out, &history{}, func(string) []string {
+		return []string{"let", "len"}
+	})
+
+	if _, err := e.readLine(PROMPT); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "len  let") {
+		t.Errorf("candidates not listed in %q", out.String())
+	}
+}
+
+func TestEditorEndsInput(t *testing.T) {
+	if _, err := newTestEditor("\x04").readLine(PROMPT); err != io.EOF {
+		t.Errorf("ctrl-d on an empty line should return io.EOF, got=%v", err)
+	}
+	if _, err := newTestEditor("abc\x03").readLine(PROMPT); !errors.Is(err, errInterrupted) {
+		t.Errorf("ctrl-c should interrupt, got=%v", err)
+	}
+	if _, err := newTestEditor("abc").readLine(PROMPT); err != io.EOF {
+		t.Errorf("end of input should return io.EOF, got=%v", err)
+	}
+}
+
+func TestEditorAddsToHistory(t *testing.T) {
+	e := newTestEditor("one\rone\r\rtwo\r")
+	for range 4 {
+		if _, err := e.readLine(PROMPT); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := strings.Join(e.history.entries, ","); got != "one,two" {
+		t.Errorf("wrong history. want=%q, got=%q", "one,two", got)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lars_history")
+
+	h := loadHistory(path)
+	if len(h.entries) != 0 {
+		t.Fatalf("missing file should give an empty history, got=%q", h.entries)
+	}
+	h.add("let x = 1;")
+	h.add("x + 1")
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != "let x = 1;\nx + 1\n" {
+		t.Errorf("wrong history file %q", saved)
+	}
+
+	if got := loadHistory(path).entries; strings.Join(got, "|") != "let x = 1;|x + 1" {
+		t.Errorf("wrong loaded history %q", got)
+	}
+}
+
+func TestHistoryFileIsCapped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lars_history")
+
+	var lines []string
+	for n := range maxHistory + 5 {
+		lines = append(lines, fmt.Sprintf("line %d", n))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := loadHistory(path)
+	if len(h.entries) != maxHistory || h.entries[0] != "line 5" {
+		t.Fatalf("wrong loaded history: %d entries from %q", len(h.entries), h.entries[0])
+	}
+	h.add("last")
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := strings.Split(strings.TrimSuffix(string(saved), "\n"), "\n")
+	if len(entries) != maxHistory || entries[0] != "line 6" || entries[len(entries)-1] != "last" {
+		t.Errorf("history file not capped: %d entries, from %q to %q", len(entries), entries[0], entries[len(entries)-1])
+	}
+	if len(h.entries) != maxHistory {
+		t.Errorf("history not capped in memory: %d entries", len(h.entries))
+	}
+}
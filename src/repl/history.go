@@ -0,0 +1,102 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is the number of history entries kept, in memory and in the
+// history file.
+const maxHistory = 1000
+
+// history is the list of lines entered, oldest first. Lines are appended
+// to a file as they are added, so that they survive the session. Once
+// there are more than maxHistory, the oldest are dropped and the file is
+// rewritten.
+type history struct {
+	entries []string
+	path    string // empty to keep history in memory only
+}
+
+// historyPath returns the path of the history file, ~/.lars_history, or
+// "" if there is no home directory.
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".lars_history")
+}
+
+// loadHistory reads the last maxHistory entries of the history file at
+// path, and drops the others from the file. A missing or unreadable file
+// gives an empty history.
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		h.rewrite()
+	}
+
+	return h
+}
+
+// add appends line to the history, unless it is empty or repeats the last
+// entry. Failing to write the file is not an error worth interrupting the
+// REPL for, so it is ignored.
+func (h *history) add(line string) {
+	if line == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return
+	}
+	h.entries = append(h.entries, line)
+
+	if h.path == "" {
+		return
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		h.rewrite()
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	f.WriteString(line + "\n")
+}
+
+// rewrite replaces the history file with the entries. The file is written
+// aside and renamed into place, so that a failure leaves the old one.
+func (h *history) rewrite() {
+	f, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(strings.Join(h.entries, "\n") + "\n")
+	if closeErr := f.Close(); err != nil || closeErr != nil {
+		return
+	}
+	os.Rename(f.Name(), h.path)
+}
@@ -0,0 +1,90 @@
+package repl
+
+import (
+	"strings"
+
+	"github.com/salty-max/lars/src/lexer"
+	"github.com/salty-max/lars/src/token"
+)
+
+// continuations are the tokens that cannot end a statement, so input ending
+// with one of them carries on on the next line.
+var continuations = map[token.TokenType]bool{
+	token.ASSIGN:     true,
+	token.PLUS:       true,
+	token.MINUS:      true,
+	token.STAR:       true,
+	token.SLASH:      true,
+	token.PERCENT:    true,
+	token.PLUS_EQ:    true,
+	token.MINUS_EQ:   true,
+	token.STAR_EQ:    true,
+	token.SLASH_EQ:   true,
+	token.PERCENT_EQ: true,
+	token.BANG:       true,
+	token.AND:        true,
+	token.OR:         true,
+	token.EQ:         true,
+	token.NOT_EQ:     true,
+	token.LT:         true,
+	token.GT:         true,
+	token.LTE:        true,
+	token.GTE:        true,
+	token.BIT_AND:    true,
+	token.BIT_OR:     true,
+	token.BIT_XOR:    true,
+	token.BIT_NOT:    true,
+	token.LSHIFT:     true,
+	token.RSHIFT:     true,
+	token.COMMA:      true,
+	token.DOT:        true,
+	token.ELSE:       true,
+}
+
+// incomplete reports whether input needs more lines before it can be
+// parsed: it has unbalanced braces, brackets or parentheses, an open
+// string, or ends with an operator.
+func incomplete(input string) bool {
+	l := lexer.New(input)
+
+	depth := 0
+	last := token.Token{Type: token.EOF}
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		case token.ILLEGAL:
+			if strings.HasPrefix(tok.Literal, `"`) {
+				return true
+			}
+		}
+		last = tok
+	}
+
+	return depth > 0 || continuations[last.Type]
+}
+
+// splitInputs splits src into the inputs it would make if its lines were
+// typed at the prompt one by one. Blank lines between inputs are dropped,
+// but unlike at the prompt they do not cut an incomplete input short, so
+// that any script can be split.
+func splitInputs(src string) []string {
+	var inputs, lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+		if len(lines) == 0 && strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+		if input := strings.Join(lines, "\n"); !incomplete(input) {
+			inputs = append(inputs, input)
+			lines = nil
+		}
+	}
+	if len(lines) != 0 {
+		inputs = append(inputs, strings.Join(lines, "\n"))
+	}
+	return inputs
+}
@@ -0,0 +1,57 @@
+package repl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"", false},
+		{"1 + 2", false},
+		{"let add = fn(x, y) {", true},
+		{"let add = fn(x, y) {\n  x + y\n}", false},
+		{"[1, 2,", true},
+		{"[1, 2,\n 3]", false},
+		{"add(1,", true},
+		{"f(", true},
+		{"let x =", true},
+		{"1 +", true},
+		{"a &&", true},
+		{"if (x) { 1 } else", true},
+		{`"hello`, true},
+		{"\"hello\nworld\"", false},
+		{`"{"`, false},
+		{"1 + 2 // trailing (", false},
+		{"}", false},
+	}
+
+	for _, tt := range tests {
+		if got := incomplete(tt.input); got != tt.expected {
+			t.Errorf("incomplete(%q) wrong. want=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSplitInputs(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{"", nil},
+		{"1 + 1\n_ * 2\n", []string{"1 + 1", "_ * 2"}},
+		{"let a = 1; a\n\n\nlet b = 2;", []string{"let a = 1; a", "let b = 2;"}},
+		{"let f = fn(x) {\n\n  x\n};\nf(1)", []string{"let f = fn(x) {\n\n  x\n};", "f(1)"}},
+		{"let x =\n  1 +\n  2", []string{"let x =\n  1 +\n  2"}},
+		{"[1,\n2", []string{"[1,\n2"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitInputs(tt.src); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("splitInputs(%q) wrong. want=%q, got=%q", tt.src, tt.expected, got)
+		}
+	}
+}
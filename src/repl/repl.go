@@ -1,70 +1,97 @@
 package repl
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"os/user"
+	"strings"
 
-	"github.com/salty-max/lars/src/evaluator"
 	"github.com/salty-max/lars/src/lexer"
 	"github.com/salty-max/lars/src/log"
-	"github.com/salty-max/lars/src/object"
 	"github.com/salty-max/lars/src/parser"
+	"github.com/salty-max/lars/src/token"
 )
 
 const PROMPT = ">> "
 
+// CONTINUATION_PROMPT is shown while the input read so far is incomplete.
+const CONTINUATION_PROMPT = ".. "
+
 // Start starts the REPL.
-func Start(in io.Reader, out io.Writer, user *user.User) {
-	logger := log.NewLogger(out)
+func Start(in io.Reader, out io.Writer, user *user.User, mode log.ColorMode) {
+	logger := log.NewLogger(out, log.WithColor(mode), log.WithTimestamps(log.IsTerminal(out)))
 	logger.Info("Lars REPL v0.1.0")
 	logger.Info(fmt.Sprintf("Hello %s!", user.Username))
+	logger.Info("Type :help for a list of commands.")
 
-	scanner := bufio.NewScanner(in)
-	for {
-		fmt.Fprintf(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
-		}
-
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+	s := newSession(out, mode)
 
-		program := p.ParseProgram()
+	// lines of the input being entered, while it is incomplete
+	var lines []string
 
-		// for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-		// 	fmt.Fprintf(out, "%+v\n", tok.Debug())
-		// }
+	reader := newLineReader(in, out, s.complete)
+	for {
+		prompt := PROMPT
+		if len(lines) != 0 {
+			prompt = CONTINUATION_PROMPT
+		}
 
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		line, err := reader.readLine(prompt)
+		if err == errInterrupted {
+			lines = nil
 			continue
 		}
+		if err != nil {
+			return
+		}
 
-		evaluated := evaluator.Eval(program)
-		if evaluated != nil {
-			if evaluated.Type() == object.ERROR_OBJ {
-				io.WriteString(out, log.Colorize(log.RED, evaluated.Inspect()))
-			} else {
-				io.WriteString(out, log.Colorize(log.GREEN, evaluated.Inspect()))
+		if len(lines) == 0 && strings.HasPrefix(line, ":") {
+			if s.command(line) {
+				return
 			}
+			continue
+		}
 
-			io.WriteString(out, "\n")
+		lines = append(lines, line)
+		input := strings.Join(lines, "\n")
+		// a blank line submits incomplete input too, so a mistake can be
+		// abandoned instead of prompting forever
+		if line != "" && incomplete(input) {
+			continue
+		}
+		lines = nil
+
+		if s.run(input) {
+			return
 		}
 	}
 }
 
-func printParserErrors(out io.Writer, errors []parser.ParserError) {
-	io.WriteString(out, log.Colorize(log.RED, "Woops! Something went awry!\n"))
+func printTokens(out io.Writer, colors log.Colors, input string) {
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		io.WriteString(out, colors.Colorize(log.CYAN, tok.Debug()+"\n"))
+	}
+}
 
-	io.WriteString(out, log.Colorize(log.RED, fmt.Sprintf("Parser has %d error(s)\n", len(errors))))
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func printParserErrors(out io.Writer, colors log.Colors, errors []parser.ParserError) {
+	io.WriteString(out, colors.Colorize(log.RED, "Woops! Something went awry!\n"))
+
+	io.WriteString(out, colors.Colorize(log.RED, fmt.Sprintf("Parser has %d error(s)\n", len(errors))))
 	for _, err := range errors {
 		io.WriteString(
 			out,
-			log.Colorize(log.RED, fmt.Sprintf("\t(%d:%d) -> %s\n", err.Line, err.Col, err.Msg)),
+			colors.Colorize(log.RED, fmt.Sprintf("\t(%d:%d) -> %s\n", err.Line, err.Col, err.Msg)),
 		)
+		if err.Hint != "" {
+			io.WriteString(out, colors.Colorize(log.CYAN, fmt.Sprintf("\t  help: %s\n", err.Hint)))
+		}
 	}
 }
@@ -0,0 +1,54 @@
+package repl
+
+import (
+	"bytes"
+	"os/user"
+	"strings"
+	"testing"
+
+	"github.com/salty-max/lars/src/log"
+)
+
+func run(t *testing.T, input string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out, &user.User{Username: "test"}, log.ColorAlways)
+	return out.String()
+}
+
+func TestMultiLineInput(t *testing.T) {
+	out := run(t, "fn(x,\n  y) {\n  x +\n    y\n}(1, 2)\n")
+
+	if got := strings.Count(out, CONTINUATION_PROMPT); got != 4 {
+		t.Errorf("wrong number of continuation prompts. want=4, got=%d in %q", got, out)
+	}
+	if !strings.Contains(out, log.Colorize(log.GREEN, "3")) {
+		t.Errorf("result missing from output %q", out)
+	}
+}
+
+func TestBlankLineSubmitsIncompleteInput(t *testing.T) {
+	out := run(t, "let x = (1 +\n\n2\n")
+
+	if !strings.Contains(out, "Parser has") {
+		t.Errorf("expected a parser error in %q", out)
+	}
+	if !strings.HasSuffix(out, PROMPT) {
+		t.Errorf("expected a fresh prompt after the error in %q", out)
+	}
+	if !strings.Contains(out, log.Colorize(log.GREEN, "2")) {
+		t.Errorf("expected the next line to be evaluated in %q", out)
+	}
+}
+
+func TestPipedOutputIsPlain(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("1 + 1\n1 + true\n"), &out, &user.User{Username: "test"}, log.ColorAuto)
+
+	expected := "Lars REPL v0.1.0\nHello test!\nType :help for a list of commands.\n" +
+		">> 2\n>> Error (1:3) -> type mismatch: INTEGER + BOOLEAN\n>> "
+	if out.String() != expected {
+		t.Errorf("piped output should have no colors or timestamps.\nwant=%q\ngot= %q", expected, out.String())
+	}
+}
@@ -0,0 +1,122 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/salty-max/lars/src/ast"
+	"github.com/salty-max/lars/src/evaluator"
+	"github.com/salty-max/lars/src/log"
+	"github.com/salty-max/lars/src/object"
+	"github.com/salty-max/lars/src/token"
+)
+
+// session is the state kept between inputs of one REPL run.
+type session struct {
+	out    io.Writer
+	colors log.Colors
+	env    *object.Environment
+
+	// inputs that parsed, in order, for :save. Those that failed are kept
+	// too, since the bindings they made before failing remain.
+	transcript []string
+
+	// toggled with :tokens and :ast to dump each input before evaluating it
+	showTokens bool
+	showAST    bool
+}
+
+func newSession(out io.Writer, mode log.ColorMode) *session {
+	s := &session{out: out, colors: log.NewColors(out, mode)}
+	s.env = s.newEnvironment()
+	return s
+}
+
+// newEnvironment creates an empty environment whose scripts print to the
+// REPL output. They get no input, since the REPL reads its own.
+func (s *session) newEnvironment() *object.Environment {
+	env := object.NewEnvironment()
+	env.SetStdout(s.out)
+	env.SetStderr(s.out)
+	return env
+}
+
+// run evaluates input in the session and prints its result. It reports
+// whether the program asked to exit.
+func (s *session) run(input string) bool {
+	if s.showTokens {
+		printTokens(s.out, s.colors, input)
+	}
+
+	program := s.parse(input)
+	if program == nil {
+		return false
+	}
+
+	if s.showAST {
+		ast.Dump(s.out, program)
+	}
+
+	evaluated := evaluator.EvalContext(context.Background(), program, s.env, evaluator.Limits{})
+	if _, ok := evaluated.(*object.Exit); ok {
+		return true
+	}
+
+	s.transcript = append(s.transcript, input)
+
+	if evaluated != nil {
+		if evaluated.Type() == object.ERROR_OBJ {
+			io.WriteString(s.out, s.colors.Colorize(log.RED, evaluated.Inspect()))
+		} else {
+			s.env.Set("_", evaluated)
+			io.WriteString(s.out, s.colors.Colorize(log.GREEN, evaluated.Inspect()))
+		}
+
+		io.WriteString(s.out, "\n")
+	}
+
+	return false
+}
+
+// printEnv lists the session bindings with the type of their value.
+func (s *session) printEnv() {
+	for _, name := range s.env.Names() {
+		val, _ := s.env.Get(name)
+		fmt.Fprintf(s.out, "%s: %s\n", name, val.Type())
+	}
+}
+
+// save writes the session transcript to path, one input after the other,
+// so that loading it back replays the session input by input.
+func (s *session) save(path string) error {
+	var src strings.Builder
+	for _, input := range s.transcript {
+		src.WriteString(input)
+		src.WriteString("\n")
+	}
+	return os.WriteFile(path, []byte(src.String()), 0o644)
+}
+
+func (s *session) error(msg string) {
+	io.WriteString(s.out, s.colors.Colorize(log.RED, msg+"\n"))
+}
+
+// complete returns the completions of prefix: meta-commands if it starts
+// with a colon, otherwise keywords, builtins and session bindings.
+func (s *session) complete(prefix string) []string {
+	var candidates []string
+	if strings.HasPrefix(prefix, ":") {
+		for _, cmd := range commands {
+			candidates = append(candidates, cmd.name)
+		}
+		return candidates
+	}
+
+	candidates = append(candidates, token.Keywords()...)
+	candidates = append(candidates, evaluator.BuiltinNames()...)
+	candidates = append(candidates, s.env.Names()...)
+	return candidates
+}
@@ -0,0 +1,126 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/salty-max/lars/src/log"
+)
+
+func TestSessionKeepsBindings(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, log.ColorAlways)
+
+	s.run("let x = 1;")
+	s.run("x + 1")
+	s.run("_ * 10")
+
+	expected := log.Colorize(log.GREEN, "2") + "\n" + log.Colorize(log.GREEN, "20") + "\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestSessionErrorsDoNotBindLastResult(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, log.ColorAlways)
+
+	s.run("5")
+	s.run("1 + true")
+
+	val, ok := s.env.Get("_")
+	if !ok || val.Inspect() != "5" {
+		t.Errorf("_ should still be 5, got=%v", val)
+	}
+	if len(s.transcript) != 2 {
+		t.Errorf("failed input should be recorded, transcript=%q", s.transcript)
+	}
+}
+
+func TestEnvCommand(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, log.ColorAlways)
+
+	s.run(`let name = "lars"; let add = fn(a, b) { a + b };`)
+	out.Reset()
+	s.command(":env")
+
+	expected := "add: FUNCTION\nname: STRING\n"
+	if out.String() != expected {
+		t.Errorf("wrong :env output. want=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestResetCommand(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, log.ColorAlways)
+
+	s.run("let x = 1;")
+	s.command(":reset")
+
+	if _, ok := s.env.Get("x"); ok {
+		t.Errorf("x still bound after :reset")
+	}
+	if len(s.transcript) != 0 {
+		t.Errorf("transcript not cleared after :reset: %q", s.transcript)
+	}
+	if s.env.Stdout() != &out || s.env.Stderr() != &out {
+		t.Errorf("scripts no longer print to the REPL output after :reset")
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.lars")
+
+	var out bytes.Buffer
+	s := newSession(&out, log.ColorAlways)
+	s.run("let x = 20;")
+	s.run("let double = fn(n) {\n  n * 2\n};")
+	s.run("nope")
+	s.command(":save " + path)
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "let x = 20;\nlet double = fn(n) {\n  n * 2\n};\nnope\n"
+	if string(saved) != expected {
+		t.Errorf("wrong transcript. want=%q, got=%q", expected, string(saved))
+	}
+
+	out.Reset()
+	loaded := newSession(&out, log.ColorAlways)
+	loaded.command(":load " + path)
+	loaded.run("double(x)")
+
+	if !strings.Contains(out.String(), log.Colorize(log.GREEN, "40")) {
+		t.Errorf("loaded session does not evaluate, output=%q", out.String())
+	}
+}
+
+func TestSaveAndLoadReplaysInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.lars")
+
+	var out bytes.Buffer
+	s := newSession(&out, log.ColorAlways)
+	s.run("1 + 1")
+	s.run("_ * 2")
+	s.run("let y = _; y + true")
+	s.command(":save " + path)
+	s.command(":reset")
+
+	out.Reset()
+	s.command(":load " + path)
+
+	expected := log.Colorize(log.GREEN, "2") + "\n" + log.Colorize(log.GREEN, "4") + "\n" +
+		log.Colorize(log.RED, "Error (1:14) -> type mismatch: INTEGER + BOOLEAN") + "\n"
+	if out.String() != expected {
+		t.Errorf("wrong replay. want=%q, got=%q", expected, out.String())
+	}
+	if y, ok := s.env.Get("y"); !ok || y.Inspect() != "4" {
+		t.Errorf("y = %v, want 4", y)
+	}
+}
@@ -0,0 +1,56 @@
+// Package suggest finds the closest match for a misspelled name.
+package suggest
+
+// Distance returns the optimal string alignment distance between a and b:
+// the number of insertions, deletions, substitutions and transpositions of
+// adjacent characters needed to turn a into b.
+func Distance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= len(b); j++ {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+// Closest returns the candidate nearest to name, provided it is close enough
+// to be a plausible typo: one edit is allowed for every three characters of
+// name, so names shorter than three characters never get a suggestion. An
+// exact match is not a suggestion. Ties go to the candidate that comes first.
+func Closest(name string, candidates []string) (string, bool) {
+	limit := len(name) / 3
+	if limit == 0 {
+		return "", false
+	}
+	best, bestDist := "", limit+1
+
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+		if dist := Distance(name, c); dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+
+	return best, best != ""
+}
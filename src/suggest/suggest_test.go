@@ -0,0 +1,51 @@
+package suggest
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"let", "let", 0},
+		{"lett", "let", 1},
+		{"esle", "else", 1},
+		{"retrun", "return", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Distance(%q, %q) wrong. want=%d, got=%d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"else", "elif", "let", "return", "counter"}
+
+	tests := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"esle", "else", true},
+		{"retrun", "return", true},
+		{"countr", "counter", true},
+		{"let", "", false},
+		{"x", "", false},
+		{"le", "", false},
+		{"banana", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Closest(tt.name, candidates)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("Closest(%q) wrong. want=(%q, %t), got=(%q, %t)",
+				tt.name, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
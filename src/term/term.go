@@ -0,0 +1,8 @@
+// Package term puts terminals in raw mode, for programs that read input a
+// key at a time.
+package term
+
+// State is a terminal mode saved by MakeRaw, to be put back by Restore.
+type State struct {
+	termios termios
+}
@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package term
+
+import "errors"
+
+type termios struct{}
+
+var errUnsupported = errors.New("term: raw mode is not supported on this platform")
+
+// IsTerminal reports whether fd refers to a terminal. It always returns
+// false on platforms without raw mode support.
+func IsTerminal(fd int) bool {
+	return false
+}
+
+// MakeRaw is not supported on this platform.
+func MakeRaw(fd int) (*State, error) {
+	return nil, errUnsupported
+}
+
+// Restore is not supported on this platform.
+func Restore(fd int, state *State) error {
+	return errUnsupported
+}
@@ -1,6 +1,9 @@
 package token
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // TokenType represents a type of token.
 type TokenType string
@@ -26,9 +29,20 @@ func LookupIdent(ident string) TokenType {
 	return IDENT
 }
 
+// Keywords returns the spelling of every keyword, sorted.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT"
 
 	// Identifiers + literals
 	IDENT  = "IDENT"